@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"math"
 	"math/big"
 	"math/rand"
 
@@ -70,8 +69,7 @@ func (s BoolSlice) Evaluate() (float64, error) {
 			series = append(series, number)
 		}
 	}
-	sum, product := sumProductTest(series)
-	score := math.Sqrt(sum*sum + product*product)
+	score := scoring.Score(newMeasure(series), *epsilon)
 	return score, nil
 }
 
@@ -0,0 +1,75 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package checkpoint saves and restores the state of long running computations.
+package checkpoint
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/pointlander/collatz/internal/atomicfile"
+)
+
+// Save writes state as JSON to path, the file is replaced atomically so an
+// interrupted save leaves the previous checkpoint intact
+func Save(path string, state interface{}) error {
+	return atomicfile.Write(path, func(out *os.File) error {
+		return json.NewEncoder(out).Encode(state)
+	})
+}
+
+// Load reads the state saved at path, it returns false if there is no checkpoint
+func Load(path string, state interface{}) (bool, error) {
+	in, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer in.Close()
+	err = json.NewDecoder(in).Decode(state)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Remove deletes the checkpoint at path if it exists
+func Remove(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Interval decides when a checkpoint is due
+type Interval struct {
+	every time.Duration
+	last  time.Time
+}
+
+// NewInterval creates an Interval that is due every period, a period of zero is never due
+func NewInterval(every time.Duration) *Interval {
+	return &Interval{
+		every: every,
+		last:  time.Now(),
+	}
+}
+
+// Due returns true and restarts the interval if a checkpoint is due
+func (i *Interval) Due() bool {
+	if i.every <= 0 {
+		return false
+	}
+	if now := time.Now(); now.Sub(i.last) >= i.every {
+		i.last = now
+		return true
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state := map[string]int{}
+	if found, err := Load(path, &state); err != nil || found {
+		t.Errorf("Load of a missing checkpoint = %v, %v", found, err)
+	}
+	for done := 1; done <= 2; done++ {
+		if err := Save(path, map[string]int{"done": done}); err != nil {
+			t.Fatal(err)
+		}
+		if found, err := Load(path, &state); err != nil || !found || state["done"] != done {
+			t.Errorf("Load = %v, %v, %v, want done %d", state, found, err, done)
+		}
+	}
+	if err := Save(path, func() {}); err == nil {
+		t.Error("saved a function")
+	}
+	if found, err := Load(path, &state); err != nil || !found || state["done"] != 2 {
+		t.Errorf("Load = %v, %v, %v after a failed save", state, found, err)
+	}
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) > 0 {
+		t.Errorf("temporary files were left: %v", matches)
+	}
+
+	if err := Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Remove left %s", path)
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("Remove of a missing checkpoint = %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package fib searches fibonacci like sequences for common factors.
+package fib
+
+import (
+	"math/big"
+)
+
+var (
+	zero  = big.NewInt(0)
+	one   = big.NewInt(1)
+	fOne  = big.NewFloat(1)
+	fTwo  = big.NewFloat(2)
+	fFive = big.NewFloat(5)
+)
+
+// Searcher returns the index of the first term that shares a factor with x*y and that factor
+type Searcher func(x, y uint64) (int, *big.Int)
+
+// Search returns a Searcher for the fibonacci like sequence starting with i0, i1
+func Search(i0, i1 int64) Searcher {
+	return func(x, y uint64) (int, *big.Int) {
+		base := big.NewInt(0)
+		base.SetUint64(x * y)
+		test := func(offset *big.Int) (bool, *big.Int) {
+			gcd, sum := big.Int{}, big.Int{}
+			sum.Add(base, offset)
+			if gcd.GCD(nil, nil, base, &sum).Cmp(one) > 0 {
+				return true, &gcd
+			}
+			return false, nil
+		}
+
+		a, b, i := big.NewInt(i0), big.NewInt(i1), 0
+		if ok, gcd := test(b); ok {
+			return i, gcd
+		}
+		i++
+		for {
+			c := big.NewInt(0)
+			c.Add(a, b)
+			a, b = b, c
+			if ok, gcd := test(b); ok {
+				return i, gcd
+			}
+			i++
+		}
+	}
+}
+
+// Binet computes the nn'th fibonacci number using Binet's formula
+func Binet(nn *big.Int) *big.Int {
+	prec := uint(1024)
+	n := big.Int{}
+	n.Set(nn)
+
+	f1, p1 := big.Float{}, big.Float{}
+	f1.SetPrec(prec).Sqrt(fFive)
+	f1.Add(fOne, &f1)
+	p1.SetPrec(prec).Set(fOne)
+
+	f2, p2 := big.Float{}, big.Float{}
+	f2.SetPrec(prec).Sqrt(fFive)
+	f2.Sub(fOne, &f2)
+	p2.SetPrec(prec).Set(fOne)
+
+	f3, p3 := big.Float{}, big.Float{}
+	f3.SetPrec(prec).Set(fTwo)
+	p3.SetPrec(prec).Set(fOne)
+
+	for n.Cmp(zero) > 0 {
+		if n.Bit(0) == 1 {
+			p1.Mul(&p1, &f1)
+			p2.Mul(&p2, &f2)
+			p3.Mul(&p3, &f3)
+		}
+		f1.Mul(&f1, &f1)
+		f2.Mul(&f2, &f2)
+		f3.Mul(&f3, &f3)
+		n.Rsh(&n, 1)
+	}
+
+	f := big.Float{}
+	f.SetPrec(prec).Sub(&p1, &p2)
+	d := big.Float{}
+	d.SetPrec(prec).Sqrt(fFive)
+	d.Mul(&p3, &d)
+	f.Quo(&f, &d)
+	output := big.Int{}
+	f.Int(&output)
+	return &output
+}
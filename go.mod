@@ -4,11 +4,11 @@ go 1.18
 
 require (
 	github.com/MaxHalford/eaopt v0.1.1-0.20190219195558-d7a315d07c40
+	github.com/VividCortex/gohistogram v1.0.0
 	gonum.org/v1/plot v0.0.0-20190221115740-81bd881d6c80
 )
 
 require (
-	github.com/VividCortex/gohistogram v1.0.0 // indirect
 	github.com/ajstarks/svgo v0.0.0-20180226025133-644b8db467af // indirect
 	github.com/fogleman/gg v1.2.1-0.20190220221249-0403632d5b90 // indirect
 	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
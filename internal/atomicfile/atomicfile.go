@@ -0,0 +1,37 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package atomicfile replaces files so that readers never see a partial write.
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Write replaces path with the file written by write, which is given a temporary file in
+// the same directory that is renamed over path once it is written and closed. If write or any
+// other step fails the temporary file is removed and path is left intact. The file is created
+// with permissions 0644
+func Write(path string, write func(out *os.File) error) error {
+	out, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	name := out.Name()
+	err = out.Chmod(0644)
+	if err == nil {
+		err = write(out)
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(name, path)
+	}
+	if err != nil {
+		os.Remove(name)
+	}
+	return err
+}
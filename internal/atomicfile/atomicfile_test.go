@@ -0,0 +1,54 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package atomicfile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	write := func(s string) func(*os.File) error {
+		return func(out *os.File) error {
+			_, err := out.WriteString(s)
+			return err
+		}
+	}
+	if err := Write(path, write("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(path, write("second")); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err := Write(path, func(out *os.File) error {
+		out.WriteString("partial")
+		return failed
+	})
+	if err != failed {
+		t.Errorf("Write = %v, want %v", err, failed)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "second" {
+		t.Errorf("%s = %q, %v after a failed write, want %q", path, data, err, "second")
+	}
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) > 0 {
+		t.Errorf("temporary files were left: %v", matches)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Mode().Perm() != 0644 {
+		t.Errorf("permissions = %v, want 0644", stat.Mode().Perm())
+	}
+
+	if err := Write(filepath.Join(path, "missing", "file"), write("")); err == nil {
+		t.Error("Write to a missing directory succeeded")
+	}
+}
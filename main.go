@@ -5,22 +5,22 @@
 package main
 
 import (
-	"bufio"
 	"compress/gzip"
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"math"
 	"math/big"
 	"math/rand"
-	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"runtime"
 	"sort"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MaxHalford/eaopt"
 	"github.com/VividCortex/gohistogram"
@@ -28,413 +28,420 @@ import (
 	"gonum.org/v1/plot/plotter"
 	"gonum.org/v1/plot/vg"
 	"gonum.org/v1/plot/vg/draw"
+
+	"github.com/pointlander/collatz/checkpoint"
+	"github.com/pointlander/collatz/fib"
+	"github.com/pointlander/collatz/oeis"
+	"github.com/pointlander/collatz/pool"
+	"github.com/pointlander/collatz/primes"
+	"github.com/pointlander/collatz/report"
+	"github.com/pointlander/collatz/series"
+	"github.com/pointlander/collatz/sumproduct"
+	"github.com/pointlander/collatz/trajectory"
 )
 
 var (
-	zero  = big.NewInt(0)
-	one   = big.NewInt(1)
-	two   = big.NewInt(2)
-	three = big.NewInt(3)
-	fOne  = big.NewFloat(1)
-	fTwo  = big.NewFloat(2)
-	fFive = big.NewFloat(5)
-	a     = &big.Int{}
-	b     = &big.Int{}
+	a         = &big.Int{}
+	b         = &big.Int{}
+	scoring   = sumproduct.Ratio
+	expanders = sumproduct.Default
+	ring      = sumproduct.Rings["integer"]
+	// modulus reduces series before scoring when it is not nil
+	modulus *big.Int
 )
 
 var (
-	number      = flag.String("number", "13", "starting number")
-	brute       = flag.Bool("brute", false, "try a bunch of numbers")
-	aa          = flag.String("a", "2", "number series parameter")
-	bb          = flag.String("b", "3", "number series parameter")
-	arithmetic  = flag.Bool("arithmetic", false, "use arithmetic integers for series")
-	geometric   = flag.Bool("geometric", false, "use geometric integers for series")
-	atomic      = flag.Bool("atomic", false, "use atomic neutron counts for series")
-	random      = flag.Bool("random", false, "use random numbers for series")
-	seven       = flag.Bool("seven", false, "use seven smooth series")
-	sevenComp   = flag.Bool("sevenComp", false, "use seven smooth complement series")
-	oeis        = flag.Bool("oeis", false, "search through oeis")
-	fibonacci   = flag.Bool("fibonacci", false, "fibonacci search")
-	printPrimes = flag.Uint64("primes", 0, "print the prime number out")
-	search      = flag.Bool("search", false, "search for series")
+	number         = flag.String("number", "13", "starting number")
+	brute          = flag.Bool("brute", false, "try a bunch of numbers")
+	aa             = flag.String("a", "2", "number series parameter")
+	bb             = flag.String("b", "3", "number series parameter")
+	arithmetic     = flag.Bool("arithmetic", false, "use arithmetic integers for series")
+	geometric      = flag.Bool("geometric", false, "use geometric integers for series")
+	atomic         = flag.Bool("atomic", false, "use atomic neutron counts for series")
+	random         = flag.Bool("random", false, "use random numbers for series")
+	seven          = flag.Bool("seven", false, "use seven smooth series")
+	sevenComp      = flag.Bool("sevenComp", false, "use seven smooth complement series")
+	oeisURL        = flag.String("oeis-url", "https://oeis.org", "where stripped.gz and names.gz are fetched from when there is no mirror")
+	oeisDir        = flag.String("oeis-dir", "", "directory of an offline OEIS mirror with stripped.gz, names.gz and b-files")
+	bfiles         = flag.Bool("bfiles", false, "score the terms of the b-files in the OEIS mirror when there are any")
+	top            = flag.Int("top", 256, "number of OEIS sequences to rank")
+	minTerms       = flag.Int("min-terms", 0, "skip OEIS sequences with fewer terms")
+	maxTerms       = flag.Int("max-terms", 0, "skip OEIS sequences with more terms, zero means no limit")
+	sign           = flag.String("sign", "any", "skip OEIS sequences by the sign of their terms: any, nonnegative or positive")
+	monotonic      = flag.Bool("monotonic", false, "only rank OEIS sequences that never decrease or never increase")
+	anumbers       = flag.String("anumbers", "", "range of A-numbers to rank such as A000001-A010000")
+	reportPath     = flag.String("report", "ranking.md", "path of the OEIS ranking report, .md, .html, .csv or .json")
+	reportFormat   = flag.String("report-format", "", "format of the report overriding the extension: markdown, html, csv or json")
+	reportTemplate = flag.String("report-template", "", "Go template file for the report")
+	headline       = flag.String("headline", "Score for seven smooth series, A002473, of different sizes:", "headline of the report")
+	plotNames      = flag.String("plots", "", "comma separated images to embed in the report, the seven smooth plot by default")
+	columnNames    = flag.String("columns", report.DefaultColumns, "comma separated columns of the report")
+	force          = flag.Bool("force", false, "allow the report to replace a README")
+	prefix         = flag.Int("prefix", 0, "score the first prefix terms of each OEIS sequence, skipping shorter ones")
+	prefixMin      = flag.Bool("prefix-min", false, "score each OEIS sequence by its minimum over the prefixes of up to prefix terms")
+	lookup         = flag.String("lookup", "", "find the OEIS sequences containing a series: arithmetic, geometric, collatz, a registered series or comma separated numbers")
+	window         = flag.Int("window", oeis.DefaultWindow, "number of consecutive terms hashed by the OEIS lookup index")
+	skipBad        = flag.Bool("skip", false, "skip malformed OEIS records instead of aborting")
+	oeisRanking    = flag.Bool("oeis", false, "search through oeis")
+	fibonacci      = flag.Bool("fibonacci", false, "fibonacci search")
+	printPrimes    = flag.Uint64("primes", 0, "print the prime number out")
+	search         = flag.Bool("search", false, "search for series")
+	maxSteps       = flag.Int("steps", trajectory.DefaultLimits.MaxSteps, "maximum number of steps in a trajectory, 0 for unlimited")
+	maxBits        = flag.Int("bits", trajectory.DefaultLimits.MaxBits, "maximum bit length of a trajectory value, 0 for unlimited")
+	stats          = flag.Bool("stats", false, "print trajectory statistics for each number in [lo, hi)")
+	lo             = flag.String("lo", "", "start of range, defaults to number")
+	hi             = flag.String("hi", "", "end of range, defaults to lo + 1")
+	verify         = flag.Bool("verify", false, "verify convergence for every number in [lo, hi)")
+	workers        = flag.Int("workers", runtime.NumCPU(), "number of workers")
+	sieveBits      = flag.Int("sieve", 16, "residue sieve modulus exponent for verify, 0 to disable, no effect with -records")
+	records        = flag.Bool("records", false, "track delay and path records in verify, every value is iterated so -sieve has no effect")
+	every          = flag.Duration("checkpoint", time.Minute, "interval between checkpoints of range runs, 0 to disable")
+	resume         = flag.Bool("resume", false, "resume range runs from their checkpoints")
+	inverse        = flag.Int("inverse", 0, "print the values that reach number within this many steps")
+	inverseDot     = flag.String("inverseDot", "", "write the inverse tree to this DOT file")
+	export         = flag.String("export", "", "write the merged trajectories to a .dot, .graphml or .json file")
+	terras         = flag.Int("terras", 0, "list the residue classes mod 2^terras with their parity vectors")
+	metricName     = flag.String("metric", "ratio", "sum-product metric: ratio, count, exponent or exact")
+	pairName       = flag.String("expanders", "sum,product",
+		"the two expanders to score: sum, product, difference, quotient, shifted, sumproduct or a composition outer-inner of sum, product, difference and quotient such as difference-product for A-A*A")
+	epsilon     = flag.Float64("epsilon", 0, "epsilon of the exponent metric, max(|A+A|, |A*A|) / |A|^(1+epsilon)")
+	energy      = flag.String("energy", "", "print the additive and multiplicative energy of a registered series")
+	size        = flag.Int("size", 256, "size of the registered series")
+	ringName    = flag.String("ring", "integer", "ring to score series in: integer, rational, gaussian or gf2")
+	modulusName = flag.String("modulus", "", "reduce series mod this modulus before forming sums and products")
+	modular     = flag.String("modular", "", "graph score versus modulus for a registered series")
+	moduli      = flag.Int("moduli", 1024, "largest modulus of the modular graph")
+	prime       = flag.Bool("prime", false, "only use prime moduli in the modular graph")
+	mapping     = flag.String("map", "3x+1", "collatz map, either qx+r or modulus:multiplier,addend,divisor;... for each residue")
 )
 
-func collatz(i *big.Int) []big.Int {
-	series := make([]big.Int, 0, 256)
-	cp := func() (z big.Int) {
-		z.Set(i)
-		return z
-	}
-	series = append(series, cp())
-	for one.Cmp(i) != 0 {
-		if i.Bit(0) == 0 {
-			i.Rsh(i, 1)
-		} else {
-			z := cp()
-			i.Lsh(i, 1).SetBit(i, 0, 1).Add(i, &z)
+// lookupSeries finds the sequences of the local stripped file that contain the lookup series
+func lookupSeries() {
+	var numbers []big.Int
+	switch *lookup {
+	case "arithmetic":
+		numbers = series.Arithmetic(a, b, *size)
+	case "geometric":
+		numbers = series.Geometric(a, b, *size)
+	case "collatz":
+		n := big.Int{}
+		if _, ok := n.SetString(*number, 10); !ok {
+			panic("invalid number")
+		}
+		m, err := trajectory.ParseMap(*mapping)
+		if err != nil {
+			panic(err)
+		}
+		result, err := m.Run(&n, trajectory.Limits{MaxSteps: *maxSteps, MaxBits: *maxBits})
+		if err != nil {
+			panic(err)
+		}
+		numbers = result.Series
+	default:
+		if source, ok := series.Registry[*lookup]; ok {
+			numbers = source.Generate(*size)
+			break
+		}
+		for _, term := range strings.Split(strings.Trim(*lookup, ", "), ",") {
+			numbers = append(numbers, big.Int{})
+			if _, ok := numbers[len(numbers)-1].SetString(strings.TrimSpace(term), 10); !ok {
+				panic("invalid number: " + term)
+			}
 		}
-		series = append(series, cp())
 	}
 
-	return series
-}
-
-func arithmeticSeries() []big.Int {
-	series := make([]big.Int, 256)
-	for i := range series {
-		x := &series[i]
-		x.SetInt64(int64(i)).Mul(b, x).Add(a, x)
+	mirror := oeis.Mirror{Dir: *oeisDir}
+	if mirror.Dir == "" {
+		mirror.Dir = "."
 	}
-	return series
-}
-
-func geometricSeries() []big.Int {
-	series := make([]big.Int, 256)
-	for i := range series {
-		x := &series[i]
-		x.SetInt64(int64(i)).Exp(b, x, nil).Mul(a, x)
+	names, err := mirror.Names()
+	if err != nil && !os.IsNotExist(err) {
+		panic(err)
 	}
-	return series
-}
-
-type Element struct {
-	Name         string  `json:"name"`
-	Appearance   string  `json:"appearance"`
-	AtomicMass   float64 `json:"atomic_mass"`
-	Boil         float64 `json:"boil"`
-	Category     string  `json:"category"`
-	Color        string  `json:"color"`
-	Density      float64 `json:"density"`
-	DiscoveredBy string  `json:"discovered_by"`
-	Melt         float64 `json:"melt"`
-	MolarHeat    float64 `json:"molar_heat"`
-	NamedBy      string  `json:"named_by"`
-	Number       int     `json:"number"`
-	Period       int     `json:"period"`
-	Phase        string  `json:"phase"`
-	Source       string  `json:"source"`
-	SpectralImg  string  `json:"spectral_img"`
-	Summary      string  `json:"summary"`
-	Symbol       string  `json:"symbol"`
-	XPos         int     `json:"xpos"`
-	YPos         int     `json:"ypos"`
-	Shells       []int   `json:"shells"`
-}
-
-type Elements struct {
-	Elements []Element `json:"elements"`
-}
-
-func atomicSeries() []big.Int {
-	series, elements, neutrons := make([]big.Int, 0, 256), Elements{}, make(map[int]bool)
-	data, err := ioutil.ReadFile("./PeriodicTableJSON.json")
+	stripped, err := mirror.Stripped()
 	if err != nil {
 		panic(err)
 	}
-	err = json.Unmarshal(data, &elements)
+	defer stripped.Close()
+	reader := oeis.NewReader(stripped)
+	reader.SkipBad = *skipBad
+	index, err := oeis.NewIndex(reader, *window)
 	if err != nil {
 		panic(err)
 	}
-	for _, element := range elements.Elements {
-		n := int(math.Round(element.AtomicMass)) - element.Number
-		neutrons[n] = true
-	}
-	sorted := make([]int, 0, len(neutrons))
-	for n := range neutrons {
-		sorted = append(sorted, n)
-	}
-	sort.Ints(sorted)
-	for _, n := range sorted {
-		series = append(series, *big.NewInt(int64(n)))
-	}
-	return series
-}
+	fmt.Println("indexed", index.Len(), "sequences")
 
-func randomSeries() []big.Int {
-	series, rnd, dupe := make([]big.Int, 256), rand.New(rand.NewSource(1)), make(map[uint64]bool)
-	for i := range series {
-		number := rnd.Uint64()
-		for dupe[number] {
-			number = rnd.Uint64()
+	matches := index.Lookup(numbers)
+	for _, match := range matches {
+		kind := "contains"
+		if match.Prefix() {
+			kind = "prefix"
+		}
+		if match.Partial {
+			kind += " partial"
 		}
-		dupe[number] = true
-		series[i].SetUint64(number)
+		fmt.Printf("%s %d %s %s\n", match.Name, match.Offset, kind, names[match.Name])
 	}
-	return series
+	fmt.Println(len(matches), "matches")
 }
 
-func fetch(url, name string) {
-	head, err := http.Head(url)
-	if err != nil {
-		panic(err)
-	}
-	size, err := strconv.Atoi(head.Header.Get("Content-Length"))
-	if err != nil {
-		panic(err)
+// writeReport writes a ranking to the report path
+func writeReport(ranked *report.Report) {
+	format, err := report.FormatOf(*reportPath)
+	if *reportFormat != "" {
+		format, err = report.ParseFormat(*reportFormat)
 	}
-	last, err := http.ParseTime(head.Header.Get("Last-Modified"))
 	if err != nil {
 		panic(err)
 	}
-	head.Body.Close()
-	stat, err := os.Stat("./" + name)
-	if err != nil || stat.ModTime().Before(last) || stat.Size() != int64(size) {
-		fmt.Println("downloading", url, "->", name, size, last)
-		response, err := http.Get(url)
-		if err != nil {
-			panic(err)
-		}
-
-		out, err := os.Create("./" + name)
-		if err != nil {
-			panic(err)
-		}
-
-		_, err = io.Copy(out, response.Body)
+	custom := ""
+	if *reportTemplate != "" {
+		data, err := os.ReadFile(*reportTemplate)
 		if err != nil {
 			panic(err)
 		}
-		response.Body.Close()
-		out.Close()
+		custom = string(data)
+	}
+	err = ranked.Save(*reportPath, format, custom, *force)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("wrote", *reportPath)
+}
 
-		err = os.Chtimes("./"+name, last, last)
-		if err != nil {
-			panic(err)
-		}
-		fmt.Println("done downloading", url, "->", name, size, last)
+// interruptible returns a context that is cancelled by the first interrupt, the handler is then
+// removed so a second interrupt kills the process
+func interruptible() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
 
+// fetch downloads url to name in the working directory when it has changed
+func fetch(ctx context.Context, url, name string) {
+	downloaded, err := oeis.NewFetcher().Fetch(ctx, url, "./"+name)
+	if err != nil {
+		panic(err)
+	}
+	if downloaded {
+		fmt.Println("done downloading", url, "->", name)
 		return
 	}
-
-	fmt.Println("skipping", url, "->", name, size, last)
+	fmt.Println("skipping", url, "->", name)
 }
 
-func oeisSearch() {
-	fetch("https://oeis.org/stripped.gz", "stripped.gz")
+// oeisSearch ranks the OEIS sequences, an interrupted search still writes the ranking so far
+func oeisSearch(ctx context.Context) {
+	mirror := oeis.Mirror{Dir: *oeisDir}
+	if mirror.Dir == "" {
+		fetch(ctx, *oeisURL+"/stripped.gz", "stripped.gz")
+		fetch(ctx, *oeisURL+"/names.gz", "names.gz")
+		mirror.Dir = "."
+	}
+	names, err := mirror.Names()
+	if os.IsNotExist(err) {
+		fmt.Println("no names in", mirror.Dir)
+	} else if err != nil {
+		panic(err)
+	}
 
-	type Series struct {
-		Name                string
-		Series              []string
-		Score, Sum, Product float64
+	filter := oeis.Filter{
+		MinTerms:  *minTerms,
+		MaxTerms:  *maxTerms,
+		Monotonic: *monotonic,
 	}
-	var sorted [256]Series
-	for i := range sorted {
-		sorted[i].Score = math.Sqrt2
+	filter.Sign, err = oeis.ParseSign(*sign)
+	if err != nil {
+		panic(err)
 	}
-	add := func(series Series) {
-		for i, a := range sorted {
-			if series.Score < a.Score || (series.Score == a.Score && strings.Compare(series.Name, a.Name) < 0) {
-				sorted[i] = series
-				for j, b := range sorted[i+1:] {
-					a, sorted[j+i+1] = b, a
-				}
-				break
-			}
+	if *anumbers != "" {
+		filter.First, filter.Last, err = oeis.ParseRange(*anumbers)
+		if err != nil {
+			panic(err)
 		}
 	}
+	if *prefixMin && *prefix < 1 {
+		panic("prefix-min needs a prefix length")
+	}
+	ranking := report.NewRanking(*top)
 
-	size := runtime.NumCPU() * 2
-	results := make(chan Series, size)
-	test := func(series Series) {
-		unique := make(map[string]bool, len(series.Series))
-		for _, number := range series.Series {
-			unique[number] = true
+	test := func(job interface{}) (interface{}, error) {
+		record := job.(oeis.Record)
+		if *bfiles {
+			terms, err := mirror.BFile(record.Name)
+			if err == nil {
+				record.Terms = terms
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+			// the terms are only filtered once they are the ones that will be scored
+			if !filter.Match(record) {
+				return nil, nil
+			}
 		}
-
-		integers, i := make([]big.Int, len(unique)), 0
-		for number := range unique {
-			_, ok := integers[i].SetString(number, 10)
+		series := report.Row{Name: record.Name, Description: names[record.Name]}
+		if *prefix > 0 {
+			measures, ok := prefixMeasures(record.Terms, *prefix, *prefixMin)
 			if !ok {
-				panic("invalid number: " + number)
+				return nil, nil
 			}
-			i++
+			// the last measure is of the first prefix terms, the others of one term less each
+			terms := *prefix
+			series.Score = math.Inf(1)
+			for i, measure := range measures {
+				if score := scoring.Score(measure, *epsilon); score < series.Score {
+					series.Score, series.Length = score, measure.Length
+					series.Sum, series.Product = scoring.Format(measure, "%f")
+					terms = *prefix - len(measures) + i + 1
+				}
+			}
+			record.Terms = record.Terms[:terms]
+			series.Numbers = record.Strings()
+			return series, nil
 		}
-		sumScore, productScore := sumProductTest(integers)
-		series.Score = math.Sqrt(sumScore*sumScore + productScore*productScore)
-		series.Sum = sumScore
-		series.Product = productScore
-		results <- series
+		series.Numbers = record.Strings()
+		measure := newMeasure(distinct(record.Terms))
+		series.Score, series.Length = scoring.Score(measure, *epsilon), measure.Length
+		series.Sum, series.Product = scoring.Format(measure, "%f")
+		return series, nil
 	}
 
-	file, err := os.Open("./stripped.gz")
+	stripped, err := mirror.Stripped()
 	if err != nil {
 		panic(err)
 	}
-	defer file.Close()
-	decoder, err := gzip.NewReader(file)
-	if err != nil {
-		panic(err)
-	}
-	defer decoder.Close()
-	reader := bufio.NewReader(decoder)
-	getSeries := func() (series Series, err error) {
-		s, err := reader.ReadString('\n')
-		if err != nil || strings.HasPrefix(s, "#") {
-			return
-		}
-		s = strings.TrimRight(s, "\n")
-		line := strings.Split(s, " ")
-		if len(line) != 2 {
-			panic("invalid file format")
+	defer stripped.Close()
+	reader := oeis.NewReader(stripped)
+	reader.SkipBad = *skipBad
+	next := func() (interface{}, bool, error) {
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return nil, false, nil
+			} else if err != nil {
+				return nil, false, err
+			}
+			if (*bfiles && filter.MatchName(record.Name)) || filter.Match(record) {
+				return record, true, nil
+			}
 		}
-		series.Name = line[0]
-		csv := strings.TrimRight(strings.TrimLeft(line[1], ","), ",")
-		series.Series = strings.Split(csv, ",")
-		return
 	}
-
-	i, series := 0, Series{}
-	for i < size {
-		series, err = getSeries()
-		if series.Name == "" {
-			continue
+	config := pool.Config{
+		Workers: *workers,
+		OnError: func(job interface{}, err error) error {
+			fmt.Println("skipping", job.(oeis.Record).Name, err)
+			return nil
+		},
+	}
+	err = pool.Run(ctx, config, next, test, func(result interface{}) error {
+		// filtered sequences and sequences shorter than the prefix have no result
+		if result != nil {
+			ranking.Add(result.(report.Row))
 		}
-		if err != nil {
-			break
-		}
-		go test(series)
-		i++
+		return nil
+	})
+	if errors.Is(err, context.Canceled) {
+		fmt.Println("interrupted, writing the partial ranking")
+	} else if err != nil {
+		panic(err)
+	}
+	if reader.Skipped > 0 {
+		fmt.Println("skipped", reader.Skipped, "malformed records")
 	}
 
-	for err == nil {
-		series = <-results
-		i--
-		add(series)
-		series, err = getSeries()
-		if err == nil {
-			i++
-			go test(series)
+	plots := []report.Plot{{Title: "seven smooth scores", Path: fmt.Sprintf("sevenSmooth%s.png", metricSuffix())}}
+	if *plotNames != "" {
+		plots = nil
+		for _, path := range strings.Split(*plotNames, ",") {
+			plots = append(plots, report.Plot{
+				Title: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+				Path:  path,
+			})
 		}
 	}
-
-	for j := 0; j < i; j++ {
-		series = <-results
-		add(series)
+	keys := *columnNames
+	if *prefixMin && keys == report.DefaultColumns {
+		keys = strings.Replace(keys, "score", "score,length", 1)
 	}
-
-	out, err := os.Create("README.md")
+	columns, err := report.ParseColumns(keys)
 	if err != nil {
 		panic(err)
 	}
-	defer out.Close()
-	fmt.Fprintf(out, "Score for seven smooth series, A002473, of different sizes:\n")
-	fmt.Fprintf(out, "![seven smooth scores](sevenSmooth.png?raw=true)\n\n")
-	fmt.Fprintf(out, "| Name | Score | Sum | Product | Numbers |\n")
-	fmt.Fprintf(out, "| ---- | ----- | --- | ------- | ------- |\n")
-	for _, series := range sorted {
-		fmt.Fprintf(out, "| [%s](https://oeis.org/%s) | %f | %f | %f | %v |\n",
-			series.Name, series.Name, series.Score, series.Sum, series.Product, series.Series)
-	}
-}
-
-var primes = [...]int{2, 3, 5, 7}
-
-func sevenSmoothSeries(size int) []big.Int {
-	series := make([]big.Int, 0, size)
-	isSmooth := func(number int) bool {
-		for _, p := range primes {
-			for number%p == 0 {
-				number /= p
-			}
+	for i := range columns {
+		switch columns[i].Key {
+		case "sum":
+			columns[i].Title = title(expanders[0])
+		case "product":
+			columns[i].Title = title(expanders[1])
 		}
-		return number == 1
 	}
-	i := 1
-	for len(series) < size {
-		if isSmooth(i) {
-			smooth := big.Int{}
-			smooth.SetInt64(int64(i))
-			series = append(series, smooth)
-		}
-		i++
+	ranked := report.Report{
+		Title:    "OEIS sum-product ranking",
+		Headline: *headline,
+		Plots:    plots,
+		Columns:  columns,
+		Rows:     ranking.Sorted(),
 	}
-	return series
-}
-
-func sevenSmoothComplementSeries(size int) []big.Int {
-	series := make([]big.Int, 0, size)
-	isSmooth := func(number int) bool {
-		for _, p := range primes {
-			for number%p == 0 {
-				number /= p
-			}
-		}
-		return number != 1
+	if scoring != sumproduct.Ratio {
+		ranked.Notes = append(ranked.Notes, fmt.Sprintf("Scores use the %s metric.", scoring))
 	}
-	i := 1
-	for len(series) < size {
-		if isSmooth(i) {
-			smooth := big.Int{}
-			smooth.SetInt64(int64(i))
-			series = append(series, smooth)
-		}
-		i++
+	if expanders != sumproduct.Default {
+		ranked.Notes = append(ranked.Notes, fmt.Sprintf("Scores use the %s and %s expanders.", expanders[0], expanders[1]))
 	}
-	return series
-}
-
-type Source struct {
-	Generate  func(size int) []big.Int
-	Key, Nice string
-}
-
-var Registry = map[string]Source{
-	"sevenSmooth": {
-		Generate: sevenSmoothSeries,
-		Key:      "sevenSmooth",
-		Nice:     "seven smooth",
-	},
-	"sevenSmoothComplement": {
-		Generate: sevenSmoothComplementSeries,
-		Key:      "sevenSmoothComplement",
-		Nice:     "seven smooth complement",
-	},
+	if ring.Key != "integer" {
+		ranked.Notes = append(ranked.Notes, fmt.Sprintf("Scores are computed over %s elements.", ring.Nice))
+	}
+	if *prefix > 0 && *prefixMin {
+		ranked.Notes = append(ranked.Notes, fmt.Sprintf("Scores are the minimum over the prefixes of up to %d terms.", *prefix))
+	} else if *prefix > 0 {
+		ranked.Notes = append(ranked.Notes, fmt.Sprintf("Scores are for the first %d terms.", *prefix))
+	}
+	writeReport(&ranked)
 }
 
-func (s Source) graph(max int) {
+// graph graphs the score of the prefixes of a series, an interrupted graph is written up to the last prefix scored
+func graph(ctx context.Context, s series.Source, max int) {
 	type Result struct {
-		Score, Sum, Product float64
-		Size                int
-	}
-	cores := runtime.NumCPU() * 2
-	results := make(chan Result, cores)
-	sample := func(size int) {
-		series := s.Generate(size)
-		sum, product := sumProductTest(series)
-		results <- Result{
-			Score:   math.Sqrt(sum*sum + product*product),
-			Sum:     sum,
-			Product: product,
-			Size:    size,
+		Score                    float64
+		Sum, Product             string
+		Size                     int
+		SumEnergy, ProductEnergy int64
+	}
+
+	points, minSize, minScore := make(plotter.XYs, 0, max), 0, math.Inf(1)
+	data, scorer := make([]Result, 0, max), sumproduct.NewPairScorer(expanders)
+	numbers, integer := s.Generate(max-1), ring.Key == "integer"
+	// energies are only tracked for integers
+	elements, ringScorer, length := []sumproduct.Element(nil), sumproduct.NewRingScorer(expanders), len(numbers)
+	if !integer {
+		elements = sumproduct.Distinct(ring.Convert(numbers))
+		length = len(elements)
+	}
+	// each prefix extends the scorer of the previous one so the prefixes are scored in order
+	for i := 0; i < length && ctx.Err() == nil; i++ {
+		var measure sumproduct.Measure
+		if integer {
+			scorer.Add(&numbers[i])
+			measure = scorer.Measure()
+		} else {
+			if err := ringScorer.Add(elements[i]); err != nil {
+				panic(err)
+			}
+			measure = ringScorer.Measure()
 		}
-	}
-
-	points, minSize, minScore := make(plotter.XYs, 0, max), 0, math.Sqrt2
-	i, j := 1, 0
-	for j < cores && i < max {
-		go sample(i)
-		j++
-		i++
-	}
-
-	data := make([]Result, 0, max)
-	for i < max {
-		result := <-results
-		j--
-		if result.Score < minScore {
-			minSize, minScore = result.Size, result.Score
+		sum, product := measure.Ratios()
+		printScores(measure.Length, sum, product)
+		result := Result{
+			Score: scoring.Score(measure, *epsilon),
+			Size:  measure.Length,
+		}
+		result.Sum, result.Product = scoring.Format(measure, "%g")
+		if integer {
+			result.SumEnergy, result.ProductEnergy = scorer.Energies()
 		}
-		points = append(points, plotter.XY{X: float64(result.Size), Y: result.Score})
-		fmt.Println(result.Size, result.Sum, result.Product, result.Score)
-		data = append(data, result)
-		go sample(i)
-		j++
-		i++
-	}
-
-	for j > 0 {
-		result := <-results
-		j--
 		if result.Score < minScore {
 			minSize, minScore = result.Size, result.Score
 		}
@@ -442,12 +449,12 @@ func (s Source) graph(max int) {
 		fmt.Println(result.Size, result.Sum, result.Product, result.Score)
 		data = append(data, result)
 	}
+	if ctx.Err() != nil {
+		fmt.Println("interrupted, writing the partial graph")
+	}
 	fmt.Println(minSize, minScore)
 
-	sort.Slice(data, func(i, j int) bool {
-		return data[i].Size < data[j].Size
-	})
-	out, err := os.Create(fmt.Sprintf("%s.csv.gz", s.Key))
+	out, err := os.Create(fmt.Sprintf("%s%s.csv.gz", s.Key, metricSuffix()))
 	if err != nil {
 		panic(err)
 	}
@@ -457,9 +464,17 @@ func (s Source) graph(max int) {
 		panic(err)
 	}
 	defer csv.Close()
-	fmt.Fprintf(csv, "size, sum, product, score\n")
-	for _, item := range data {
-		fmt.Fprintf(csv, "%d, %g, %g, %g\n", item.Size, item.Sum, item.Product, item.Score)
+	if !integer {
+		fmt.Fprintf(csv, "size, sum, product, score\n")
+		for _, item := range data {
+			fmt.Fprintf(csv, "%d, %s, %s, %g\n", item.Size, item.Sum, item.Product, item.Score)
+		}
+	} else {
+		fmt.Fprintf(csv, "size, sum, product, score, sum energy, product energy\n")
+		for _, item := range data {
+			fmt.Fprintf(csv, "%d, %s, %s, %g, %d, %d\n", item.Size, item.Sum, item.Product, item.Score,
+				item.SumEnergy, item.ProductEnergy)
+		}
 	}
 
 	p, err := plot.New()
@@ -468,8 +483,17 @@ func (s Source) graph(max int) {
 	}
 
 	p.Title.Text = fmt.Sprintf("score vs size for %s numbers", s.Nice)
+	if !integer {
+		p.Title.Text = fmt.Sprintf("score vs size for %s numbers as %s elements", s.Nice, ring.Nice)
+	}
 	p.X.Label.Text = "size"
 	p.Y.Label.Text = "score"
+	if scoring != sumproduct.Ratio {
+		p.Y.Label.Text = fmt.Sprintf("%s score", scoring)
+	}
+	if expanders != sumproduct.Default {
+		p.Y.Label.Text = fmt.Sprintf("%s (%s)", p.Y.Label.Text, expanders)
+	}
 
 	scatter, err := plotter.NewScatter(points)
 	if err != nil {
@@ -479,210 +503,170 @@ func (s Source) graph(max int) {
 	scatter.GlyphStyle.Shape = draw.CircleGlyph{}
 	p.Add(scatter)
 
-	err = p.Save(8*vg.Inch, 8*vg.Inch, fmt.Sprintf("%s.png", s.Key))
+	err = p.Save(8*vg.Inch, 8*vg.Inch, fmt.Sprintf("%s%s.png", s.Key, metricSuffix()))
 	if err != nil {
 		panic(err)
 	}
 }
 
-func searchSeries() {
-	ga, err := eaopt.NewDefaultGAConfig().NewGA()
-	if err != nil {
-		panic(err)
+// modularGraph graphs the score of a registered series reduced mod each modulus up to moduli
+func modularGraph(s series.Source, length int) {
+	if s.Generate == nil {
+		panic("unknown series: " + *modular)
+	}
+	var values []uint64
+	if *prime {
+		values = primes.Sieve(uint64(*moduli) + 1)
+	} else {
+		for m := 2; m <= *moduli; m++ {
+			values = append(values, uint64(m))
+		}
 	}
 
-	ga.NGenerations = 100
-	ga.RNG = rand.New(rand.NewSource(1))
-	ga.ParallelEval = true
-	ga.PopSize = 100
-
-	ga.Callback = func(ga *eaopt.GA) {
-		fmt.Printf("Best fitness at generation %d: %f\n", ga.Generations, ga.HallOfFame[0].Fitness)
-		fmt.Println(ga.HallOfFame[0].Genome.(BoolSlice).String())
+	numbers := s.Generate(length)
+	points, minModulus, minScore := make(plotter.XYs, 0, len(values)), uint64(0), math.Inf(1)
+	name := fmt.Sprintf("%s_modular%s.csv.gz", s.Key, metricSuffix())
+	out, err := os.Create(name)
+	if err != nil {
+		panic(err)
 	}
-
-	err = ga.Minimize(BoolSliceFactory)
+	defer out.Close()
+	csv, err := gzip.NewWriterLevel(out, gzip.BestCompression)
 	if err != nil {
 		panic(err)
 	}
-}
-
-func sumProductTest(series []big.Int) (float64, float64) {
-	length := len(series)
-	sums, products := make(map[string]int, length*length), make(map[string]int, length*length)
-	for _, x := range series {
-		for _, y := range series {
-			sum, product := big.Int{}, big.Int{}
-			sum.Add(&x, &y)
-			sums[sum.Text(2)]++
-			product.Mul(&x, &y)
-			products[product.Text(2)]++
-		}
-	}
-	max := (length * (length + 1)) / 2
-	sumScore, productScore := float64(len(sums))/float64(max), float64(len(products))/float64(max)
-	if !*oeis && !*seven && !*search {
-		fmt.Println(max, sumScore, productScore)
+	defer csv.Close()
+	fmt.Fprintf(csv, "modulus, residues, sums, products, score\n")
+	for _, m := range values {
+		measure, err := sumproduct.NewModularMeasure(numbers, new(big.Int).SetUint64(m))
+		if err != nil {
+			panic(err)
+		}
+		score := scoring.Score(measure, *epsilon)
+		if score < minScore {
+			minModulus, minScore = m, score
+		}
+		points = append(points, plotter.XY{X: float64(m), Y: score})
+		fmt.Fprintf(csv, "%d, %d, %d, %d, %g\n", m, measure.Length, measure.Sums, measure.Products, score)
 	}
-	return sumScore, productScore
-}
-
-func factor(a big.Int) []big.Int {
-	number, primes, x := big.Int{}, make([]big.Int, 0, 256), big.Int{}
-	number.Set(&a)
+	fmt.Println(minModulus, minScore)
 
-	for x.Mod(&number, two).Cmp(zero) == 0 {
-		primes = append(primes, *two)
-		number.Div(&number, two)
+	p, err := plot.New()
+	if err != nil {
+		panic(err)
 	}
 
-	i := big.NewInt(3)
-	for x.Mul(i, i).Cmp(&number) <= 0 {
-		for x.Mod(&number, i).Cmp(zero) == 0 {
-			y := big.Int{}
-			y.Set(i)
-			primes = append(primes, y)
-			number.Div(&number, i)
-		}
-		i.Add(i, two)
+	p.Title.Text = fmt.Sprintf("score vs modulus for %d %s numbers", length, s.Nice)
+	p.X.Label.Text = "modulus"
+	p.Y.Label.Text = "score"
+	if scoring != sumproduct.Ratio {
+		p.Y.Label.Text = fmt.Sprintf("%s score", scoring)
 	}
 
-	if number.Cmp(two) > 0 {
-		y := big.Int{}
-		y.Set(&number)
-		primes = append(primes, y)
+	scatter, err := plotter.NewScatter(points)
+	if err != nil {
+		panic(err)
 	}
+	scatter.GlyphStyle.Radius = vg.Length(1)
+	scatter.GlyphStyle.Shape = draw.CircleGlyph{}
+	p.Add(scatter)
 
-	return primes
-}
-
-type Searcher func(x, y uint64) (int, *big.Int)
-
-func fibonacciSearch(i0, i1 int64) Searcher {
-	return func(x, y uint64) (int, *big.Int) {
-		base := big.NewInt(0)
-		base.SetUint64(x * y)
-		test := func(offset *big.Int) (bool, *big.Int) {
-			gcd, sum := big.Int{}, big.Int{}
-			sum.Add(base, offset)
-			if gcd.GCD(nil, nil, base, &sum).Cmp(one) > 0 {
-				return true, &gcd
-			}
-			return false, nil
-		}
-
-		a, b, i := big.NewInt(i0), big.NewInt(i1), 0
-		//fmt.Println(a)
-		//fmt.Println(b)
-		if ok, gcd := test(b); ok {
-			return i, gcd
-		}
-		i++
-		for {
-			c := big.NewInt(0)
-			c.Add(a, b)
-			a, b = b, c
-			//fmt.Println(c)
-			if ok, gcd := test(b); ok {
-				return i, gcd
-			}
-			i++
-		}
+	err = p.Save(8*vg.Inch, 8*vg.Inch, fmt.Sprintf("%s_modular%s.png", s.Key, metricSuffix()))
+	if err != nil {
+		panic(err)
 	}
 }
 
-type PrimeSource interface {
-	Next() (x, y uint64)
-	More() bool
-}
-
-type SequentialSource struct {
-	Primes []uint64
-	I      int
-}
-
-func NewSequentialSource(max uint64) *SequentialSource {
-	primes := sieveOfEratosthenes(max)
-	return &SequentialSource{
-		Primes: primes,
+func searchSeries() {
+	ga, err := eaopt.NewDefaultGAConfig().NewGA()
+	if err != nil {
+		panic(err)
 	}
-}
-
-func (s *SequentialSource) Next() (x, y uint64) {
-	x, y = s.Primes[s.I], s.Primes[s.I+1]
-	s.I++
-	return
-}
 
-func (s *SequentialSource) More() bool {
-	return s.I < len(s.Primes)-1
-}
-
-type RandomSource struct {
-	Primes []uint64
-	I      int
-}
+	ga.NGenerations = 100
+	ga.RNG = rand.New(rand.NewSource(1))
+	ga.ParallelEval = true
+	ga.PopSize = 100
 
-func NewRandomSource(max uint64) *RandomSource {
-	primes := sieveOfEratosthenes(max)
-	return &RandomSource{
-		Primes: primes,
+	ga.Callback = func(ga *eaopt.GA) {
+		fmt.Printf("Best fitness at generation %d: %f\n", ga.Generations, ga.HallOfFame[0].Fitness)
+		fmt.Println(ga.HallOfFame[0].Genome.(BoolSlice).String())
 	}
-}
-
-func (r *RandomSource) Next() (x, y uint64) {
-	length := len(r.Primes)
-	x, y = r.Primes[rand.Intn(length)], r.Primes[rand.Intn(length)]
-	r.I++
-	return
-}
 
-func (r *RandomSource) More() bool {
-	return r.I < len(r.Primes)-1
+	err = ga.Minimize(BoolSliceFactory)
+	if err != nil {
+		panic(err)
+	}
 }
 
-func fibonacciGraph(name string, source PrimeSource, searchers []Searcher) {
+// fibonacciGraph graphs the Fibonacci index of the pairs of primes from source, an interrupted
+// graph is checkpointed and written up to the last pair in order
+func fibonacciGraph(ctx context.Context, name string, source primes.Source, searchers []fib.Searcher) {
 	type Result struct {
 		X, Y, Index uint64
 		GCD         *big.Int
 	}
-	cores := runtime.NumCPU() * 2
-	results := make(chan Result, cores)
-	factor := func(x, y uint64) {
+	type Pair struct {
+		X, Y uint64
+	}
+	factor := func(job interface{}) (interface{}, error) {
+		pair := job.(Pair)
 		var index uint64 = math.MaxUint64
 		var gcd *big.Int
 		for _, searcher := range searchers {
-			i, g := searcher(x, y)
+			i, g := searcher(pair.X, pair.Y)
 			if uint64(i) < index {
 				index, gcd = uint64(i), g
 			}
 		}
-		results <- Result{
-			X:     x,
-			Y:     y,
+		return Result{
+			X:     pair.X,
+			Y:     pair.Y,
 			Index: index,
 			GCD:   gcd,
-		}
+		}, nil
 	}
 
-	data, routines := make([]Result, 0, len(primes)-1), 0
-	for source.More() {
-		if routines < cores {
-			go factor(source.Next())
-			routines++
-			continue
-		}
-		result := <-results
-		data = append(data, result)
-		routines--
+	// Done counts the pairs from source whose results are all in Data
+	state := struct {
+		Done int
+		Data []Result
+	}{Data: make([]Result, 0, 256)}
+	loadCheckpoint(name, &state)
+	for _, result := range state.Data {
 		fmt.Printf("%d %d %d %v\n", result.X, result.Y, result.Index, result.GCD)
 	}
-	for routines > 0 {
-		result := <-results
-		data = append(data, result)
-		routines--
+	for i := 0; i < state.Done && source.More(); i++ {
+		source.Next()
+	}
+
+	next := func() (interface{}, bool, error) {
+		if !source.More() {
+			return nil, false, nil
+		}
+		x, y := source.Next()
+		return Pair{X: x, Y: y}, true, nil
+	}
+	interval := checkpoint.NewInterval(*every)
+	err := pool.Run(ctx, pool.Config{Workers: *workers, Ordered: true}, next, factor, func(value interface{}) error {
+		result := value.(Result)
 		fmt.Printf("%d %d %d %v\n", result.X, result.Y, result.Index, result.GCD)
+		state.Data = append(state.Data, result)
+		state.Done++
+		if interval.Due() {
+			saveCheckpoint(name, &state)
+		}
+		return nil
+	})
+	if errors.Is(err, context.Canceled) {
+		saveCheckpoint(name, &state)
+		fmt.Println("interrupted, writing the partial graph")
+	} else if err != nil {
+		panic(err)
+	} else {
+		removeCheckpoint(name)
 	}
+	data := state.Data
 
 	sort.Slice(data, func(i, j int) bool {
 		return data[i].X < data[j].X
@@ -703,7 +687,7 @@ func fibonacciGraph(name string, source PrimeSource, searchers []Searcher) {
 		fmt.Fprintf(csv, "%d, %d, %d, %v\n", item.X, item.Y, item.Index, item.GCD)
 	}
 
-	points := make(plotter.XYs, 0, len(primes)-1)
+	points := make(plotter.XYs, 0, len(data))
 	for _, item := range data {
 		points = append(points, plotter.XY{X: float64(item.GCD.Uint64()), Y: float64(item.Index)})
 	}
@@ -752,60 +736,364 @@ func fibonacciGraph(name string, source PrimeSource, searchers []Searcher) {
 	}
 }
 
-func binet(nn *big.Int) {
-	prec := uint(1024)
-	n := big.Int{}
-	n.Set(nn)
-
-	f1, p1 := big.Float{}, big.Float{}
-	f1.SetPrec(prec).Sqrt(fFive)
-	f1.Add(fOne, &f1)
-	p1.SetPrec(prec).Set(fOne)
+func parseRange() (start, end *big.Int) {
+	start, end = &big.Int{}, &big.Int{}
+	from := *lo
+	if from == "" {
+		from = *number
+	}
+	if _, ok := start.SetString(from, 10); !ok {
+		panic("invalid string for lo")
+	}
+	if *hi == "" {
+		end.Add(start, big.NewInt(1))
+	} else if _, ok := end.SetString(*hi, 10); !ok {
+		panic("invalid string for hi")
+	}
+	return start, end
+}
 
-	f2, p2 := big.Float{}, big.Float{}
-	f2.SetPrec(prec).Sqrt(fFive)
-	f2.Sub(fOne, &f2)
-	p2.SetPrec(prec).Set(fOne)
+func statistics() {
+	start, end := parseRange()
+	fmt.Printf("n, total stopping time, stopping time, glide, max excursion, max step, odd steps, parity\n")
+	for n := start; n.Cmp(end) < 0; n.Add(n, big.NewInt(1)) {
+		series, err := trajectory.Collatz(n)
+		if err != nil {
+			panic(err)
+		}
+		s := trajectory.NewStats(series)
+		fmt.Printf("%v, %d, %d, %d, %v, %d, %d, %v\n", n, s.TotalStoppingTime, s.StoppingTime, s.Glide,
+			&s.MaxExcursion, s.MaxStep, s.OddSteps, s.Parity)
+	}
+}
 
-	f3, p3 := big.Float{}, big.Float{}
-	f3.SetPrec(prec).Set(fTwo)
-	p3.SetPrec(prec).Set(fOne)
+func verification(ctx context.Context) {
+	start, end := parseRange()
+	if !start.IsUint64() || !end.IsUint64() {
+		panic("range must fit in 64 bits")
+	}
+	printRecord := func(record trajectory.Record) {
+		fmt.Printf("%s record %d %d %v\n", record.Kind, record.N, record.Delay, &record.Excursion)
+	}
+	name := fmt.Sprintf("verify_%v_%v", start, end)
+	var resumed *trajectory.Verification
+	state := trajectory.Verification{}
+	loadCheckpoint(name, &state)
+	if state.Done != 0 {
+		resumed = &state
+		for _, record := range state.Records() {
+			printRecord(record)
+		}
+	}
+	interval := checkpoint.NewInterval(*every)
+	result, err := trajectory.Verify(trajectory.VerifyConfig{
+		Lo:        start.Uint64(),
+		Hi:        end.Uint64(),
+		Workers:   *workers,
+		SieveBits: *sieveBits,
+		Records:   *records,
+		MaxSteps:  *maxSteps,
+		OnRecord:  printRecord,
+		Resume:    resumed,
+		OnProgress: func(progress *trajectory.Verification) {
+			if interval.Due() {
+				saveCheckpoint(name, progress)
+			}
+		},
+		Context: ctx,
+	})
+	if errors.Is(err, context.Canceled) {
+		saveCheckpoint(name, &result)
+		fmt.Println("interrupted, saved", checkpointPath(name))
+		return
+	} else if err != nil {
+		panic(err)
+	}
+	removeCheckpoint(name)
+	fmt.Println("verified", start, end, "checked", result.Checked, "skipped", result.Skipped)
+}
 
-	for n.Cmp(zero) > 0 {
-		if n.Bit(0) == 1 {
-			p1.Mul(&p1, &f1)
-			p2.Mul(&p2, &f2)
-			p3.Mul(&p3, &f3)
+func inverseTree() {
+	target := big.Int{}
+	if _, ok := target.SetString(*number, 10); !ok {
+		panic("invalid number")
+	}
+	tree, err := trajectory.InverseTree(&target, *inverse)
+	if err != nil {
+		panic(err)
+	}
+	for k := 0; k <= tree.Depth(); k++ {
+		level := tree.Level(k)
+		fmt.Printf("%d %d [", k, len(level))
+		for _, node := range level {
+			fmt.Printf("%v, ", &node.Value)
+		}
+		fmt.Printf("]\n")
+	}
+	if *inverseDot != "" {
+		out, err := os.Create(*inverseDot)
+		if err != nil {
+			panic(err)
 		}
-		f1.Mul(&f1, &f1)
-		f2.Mul(&f2, &f2)
-		f3.Mul(&f3, &f3)
-		n.Rsh(&n, 1)
+		defer out.Close()
+		err = tree.WriteDOT(out)
+		if err != nil {
+			panic(err)
+		}
+	}
+}
+
+func exportGraph(g *trajectory.Graph, name string) {
+	var write func(io.Writer) error
+	switch filepath.Ext(name) {
+	case ".dot", ".gv":
+		write = g.WriteDOT
+	case ".graphml":
+		write = g.WriteGraphML
+	case ".json":
+		write = g.WriteJSON
+	default:
+		panic("unknown graph format: " + name)
+	}
+	out, err := os.Create(name)
+	if err != nil {
+		panic(err)
+	}
+	defer out.Close()
+	err = write(out)
+	if err != nil {
+		panic(err)
 	}
+}
 
-	f := big.Float{}
-	f.SetPrec(prec).Sub(&p1, &p2)
-	d := big.Float{}
-	d.SetPrec(prec).Sqrt(fFive)
-	d.Mul(&p3, &d)
-	f.Quo(&f, &d)
-	output := big.Int{}
-	f.Int(&output)
-	fmt.Println(&output)
+func terrasClasses() {
+	classes, err := trajectory.Classes(*terras)
+	if err != nil {
+		panic(err)
+	}
+	descends := 0
+	fmt.Printf("residue, parity, odd, coefficient, descends\n")
+	for _, class := range classes {
+		fmt.Printf("%d, %v, %d, %g, %t\n", class.Residue, class.Parity, class.Odd, class.Coefficient, class.Descends)
+		if class.Descends {
+			descends++
+		}
+	}
+	fmt.Println(descends, "of", len(classes), "classes descend")
 }
 
-func sieveOfEratosthenes(n uint64) (primes []uint64) {
-	b := make([]bool, n)
-	for i := uint64(2); i < n; i++ {
-		if b[i] {
+// distinct returns numbers with duplicates removed, other rings may pair up consecutive terms so
+// only integers are deduplicated here
+func distinct(numbers []big.Int) []big.Int {
+	if ring.Key != "integer" {
+		return numbers
+	}
+	unique := make(map[string]bool, len(numbers))
+	integers := make([]big.Int, 0, len(numbers))
+	for i := range numbers {
+		number := numbers[i].String()
+		if unique[number] {
 			continue
 		}
-		primes = append(primes, i)
-		for j := i * i; j < n; j += i {
-			b[j] = true
+		unique[number] = true
+		integers = append(integers, numbers[i])
+	}
+	return integers
+}
+
+// prefixMeasures measures the distinct values of the first n terms of numbers, or of every prefix
+// of up to n terms when all is set, it returns false when there are fewer than n terms
+func prefixMeasures(numbers []big.Int, n int, all bool) ([]sumproduct.Measure, bool) {
+	if len(numbers) < n {
+		return nil, false
+	}
+	numbers = numbers[:n]
+	var measures []sumproduct.Measure
+	if !all {
+		return append(measures, newMeasure(distinct(numbers))), true
+	}
+	if ring.Key != "integer" {
+		scorer, seen := sumproduct.NewRingScorer(expanders), make(map[string]bool, n)
+		for _, element := range ring.Convert(numbers) {
+			if key := element.Key(); !seen[key] {
+				seen[key] = true
+				if err := scorer.Add(element); err != nil {
+					panic(err)
+				}
+			}
+			measures = append(measures, scorer.Measure())
+		}
+		return measures, true
+	}
+	if modulus != nil {
+		for i := 1; i <= n; i++ {
+			measures = append(measures, newMeasure(distinct(numbers[:i])))
 		}
+		return measures, true
 	}
-	return
+	scorer, seen := sumproduct.NewPairScorer(expanders), make(map[string]bool, n)
+	for i := range numbers {
+		if number := numbers[i].String(); !seen[number] {
+			seen[number] = true
+			scorer.Add(&numbers[i])
+		}
+		measures = append(measures, scorer.Measure())
+	}
+	return measures, true
+}
+
+// newMeasure measures series with the selected expanders in the selected ring or mod the selected modulus
+func newMeasure(numbers []big.Int) sumproduct.Measure {
+	if ring.Key != "integer" {
+		measure, err := sumproduct.NewRingMeasure(sumproduct.Distinct(ring.Convert(numbers)), expanders)
+		if err != nil {
+			panic(err)
+		}
+		return measure
+	}
+	if modulus == nil {
+		measure, err := sumproduct.NewPairMeasure(numbers, expanders)
+		if err != nil {
+			panic(err)
+		}
+		return measure
+	}
+	measure, err := sumproduct.NewModularMeasure(numbers, modulus)
+	if err != nil {
+		panic(err)
+	}
+	return measure
+}
+
+func sumProductTest(series []big.Int) (float64, float64) {
+	measure := newMeasure(series)
+	sumScore, productScore := measure.Ratios()
+	printScores(measure.Length, sumScore, productScore)
+	return sumScore, productScore
+}
+
+func energies() {
+	source, ok := series.Registry[*energy]
+	if !ok {
+		panic("unknown series: " + *energy)
+	}
+	numbers := source.Generate(*size)
+	e := sumproduct.NewEnergy(numbers)
+	sum, product := e.Normalized(len(numbers))
+	fmt.Println("additive energy", e.Sum, sum)
+	fmt.Println("multiplicative energy", e.Product, product)
+	show := func(name string, histogram map[int]int) {
+		keys := make([]int, 0, len(histogram))
+		for key := range histogram {
+			keys = append(keys, key)
+		}
+		sort.Ints(keys)
+		fmt.Println(name, "representations, count")
+		for _, key := range keys {
+			fmt.Printf("%d, %d\n", key, histogram[key])
+		}
+	}
+	show("sum", e.SumHistogram)
+	show("product", e.ProductHistogram)
+}
+
+// metricSuffix distinguishes the output files of metrics, expanders and rings other than the default
+func metricSuffix() string {
+	suffix := ""
+	if scoring != sumproduct.Ratio {
+		suffix += "_" + scoring.String()
+	}
+	if expanders != sumproduct.Default {
+		suffix += "_" + expanders[0].String() + "_" + expanders[1].String()
+	}
+	if ring.Key != "integer" {
+		suffix += "_" + ring.Key
+	}
+	return suffix
+}
+
+// title returns the name of an expander for a table heading
+func title(expander sumproduct.Expander) string {
+	name := expander.String()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+func printScores(length int, sum, product float64) {
+	if !quiet() {
+		fmt.Println(sumproduct.Max(length), sum, product)
+	}
+}
+
+func quiet() bool {
+	return *oeisRanking || *seven || *search
+}
+
+func checkpointPath(name string) string {
+	return fmt.Sprintf("%s.checkpoint.json", name)
+}
+
+func loadCheckpoint(name string, state interface{}) {
+	if !*resume {
+		return
+	}
+	found, err := checkpoint.Load(checkpointPath(name), state)
+	if err != nil {
+		panic(err)
+	}
+	if found {
+		fmt.Fprintln(os.Stderr, "resuming from", checkpointPath(name))
+	}
+}
+
+func saveCheckpoint(name string, state interface{}) {
+	err := checkpoint.Save(checkpointPath(name), state)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func removeCheckpoint(name string) {
+	err := checkpoint.Remove(checkpointPath(name))
+	if err != nil {
+		panic(err)
+	}
+}
+
+func bruteForce(ctx context.Context) {
+	type Score struct {
+		Max          int
+		Sum, Product float64
+	}
+	state := struct {
+		Next   int
+		Scores []Score
+	}{Next: 1}
+	loadCheckpoint("brute", &state)
+	if !quiet() {
+		for _, score := range state.Scores {
+			fmt.Println(score.Max, score.Sum, score.Product)
+		}
+	}
+
+	interval := checkpoint.NewInterval(*every)
+	for i := state.Next; i < 1024; i++ {
+		if ctx.Err() != nil {
+			saveCheckpoint("brute", &state)
+			fmt.Println("interrupted, saved", checkpointPath("brute"))
+			return
+		}
+		series, err := trajectory.Collatz(big.NewInt(int64(i)))
+		if err != nil {
+			panic(err)
+		}
+		sum, product := sumProductTest(series)
+		state.Scores = append(state.Scores, Score{Max: sumproduct.Max(len(series)), Sum: sum, Product: product})
+		state.Next = i + 1
+		if interval.Due() {
+			saveCheckpoint("brute", &state)
+		}
+	}
+	removeCheckpoint("brute")
 }
 
 func main() {
@@ -819,17 +1107,41 @@ func main() {
 	if !ok {
 		panic("invalid string for parameter b")
 	}
+	var err error
+	scoring, err = sumproduct.ParseMetric(*metricName)
+	if err != nil {
+		panic(err)
+	}
+	expanders, err = sumproduct.ParsePair(*pairName)
+	if err != nil {
+		panic(err)
+	}
+	ring, err = sumproduct.ParseRing(*ringName)
+	if err != nil {
+		panic(err)
+	}
+	if err := ring.Check(expanders); err != nil {
+		panic(err)
+	}
+	if *modulusName != "" {
+		modulus = &big.Int{}
+		if _, ok := modulus.SetString(*modulusName, 10); !ok {
+			panic("invalid string for parameter modulus")
+		}
+		if expanders != sumproduct.Default || ring.Key != "integer" {
+			panic("modular scoring only supports sums and products of integers")
+		}
+	}
 
 	if *brute {
-		for i := 1; i < 1024; i++ {
-			series := collatz(big.NewInt(int64(i)))
-			sumProductTest(series)
-		}
+		ctx, stop := interruptible()
+		defer stop()
+		bruteForce(ctx)
 		return
 	}
 
 	if *arithmetic {
-		series := arithmeticSeries()
+		series := series.Arithmetic(a, b, 256)
 		for _, item := range series {
 			fmt.Println(&item)
 		}
@@ -837,7 +1149,7 @@ func main() {
 		return
 	}
 	if *geometric {
-		series := geometricSeries()
+		series := series.Geometric(a, b, 256)
 		for _, item := range series {
 			fmt.Println(&item)
 		}
@@ -845,7 +1157,10 @@ func main() {
 		return
 	}
 	if *atomic {
-		series := atomicSeries()
+		series, err := series.Atomic("./PeriodicTableJSON.json")
+		if err != nil {
+			panic(err)
+		}
 		for _, item := range series {
 			fmt.Println(&item)
 		}
@@ -853,60 +1168,100 @@ func main() {
 		return
 	}
 	if *random {
-		series := randomSeries()
+		series := series.Random(256, 1)
 		for _, item := range series {
 			fmt.Println(&item)
 		}
 		sumProductTest(series)
 		return
 	}
-	if *oeis {
-		oeisSearch()
+	if *oeisRanking {
+		ctx, stop := interruptible()
+		defer stop()
+		oeisSearch(ctx)
 		return
 	}
 	if *seven {
-		series := sevenSmoothSeries(100)
-		for _, number := range series {
+		numbers := series.SevenSmooth(100)
+		for _, number := range numbers {
 			fmt.Printf(" %s", number.String())
 		}
 		fmt.Printf("\n")
 
-		Registry["sevenSmooth"].graph(256)
+		ctx, stop := interruptible()
+		defer stop()
+		graph(ctx, series.Registry["sevenSmooth"], 256)
 		return
 	}
 	if *sevenComp {
-		series := sevenSmoothComplementSeries(1024)
-		for _, number := range series {
+		numbers := series.SevenSmoothComplement(1024)
+		for _, number := range numbers {
 			fmt.Printf(" %s", number.String())
 		}
 		fmt.Printf("\n")
-		sum, product := sumProductTest(series)
-		fmt.Println(math.Sqrt(sum*sum + product*product))
-
-		Registry["sevenSmoothComplement"].graph(2048)
+		measure := newMeasure(numbers)
+		sum, product := measure.Ratios()
+		printScores(measure.Length, sum, product)
+		fmt.Println(scoring.Score(measure, *epsilon))
+
+		ctx, stop := interruptible()
+		defer stop()
+		graph(ctx, series.Registry["sevenSmoothComplement"], 2048)
 		return
 	}
 	if *search {
 		searchSeries()
 		return
 	}
+	if *energy != "" {
+		energies()
+		return
+	}
+	if *lookup != "" {
+		lookupSeries()
+		return
+	}
+	if *modular != "" {
+		modularGraph(series.Registry[*modular], *size)
+		return
+	}
 	if *fibonacci {
-		//i, gcd := fibonacciSearch(99989, 99991)
+		//i, gcd := fib.Search(0, 1)(99989, 99991)
 		//fmt.Println("found", gcd, i)
-		source := NewSequentialSource(50000)
-		fibonacciGraph("fibonacci", source, []Searcher{fibonacciSearch(0, 1)})
-		//source := NewRandomSource(50000)
-		//fibonacciGraph("random", source, []Searcher{fibonacciSearch(0, 1)})
-		//fibonacciGraph("lucas", source, []Searcher{fibonacciSearch(2, 1)})
-		//fibonacciGraph("combined", source, []Searcher{fibonacciSearch(0, 1), fibonacciSearch(2, 1)})
+		source := primes.NewSequentialSource(50000)
+		ctx, stop := interruptible()
+		defer stop()
+		fibonacciGraph(ctx, "fibonacci", source, []fib.Searcher{fib.Search(0, 1)})
+		//source := primes.NewRandomSource(50000)
+		//fibonacciGraph(ctx, "random", source, []fib.Searcher{fib.Search(0, 1)})
+		//fibonacciGraph(ctx, "lucas", source, []fib.Searcher{fib.Search(2, 1)})
+		//fibonacciGraph(ctx, "combined", source, []fib.Searcher{fib.Search(0, 1), fib.Search(2, 1)})
 
 		//n := big.Int{}
 		//n.SetString(*number, 10)
-		//binet(&n)
+		//fmt.Println(fib.Binet(&n))
+		return
+	}
+	if *verify {
+		ctx, stop := interruptible()
+		defer stop()
+		verification(ctx)
+		return
+	}
+	if *stats {
+		statistics()
+		return
+	}
+	if *inverse > 0 {
+		inverseTree()
+		return
+	}
+	if *terras > 0 {
+		terrasClasses()
 		return
 	}
 	if *printPrimes > 0 {
-		p := sieveOfEratosthenes(*printPrimes)
+		p := primes.Sieve(*printPrimes)
 		for _, i := range p {
 			fmt.Printf("%d ", i)
 		}
@@ -919,29 +1274,58 @@ func main() {
 	if !ok {
 		panic("invalid number")
 	}
-	series := collatz(&i)
+	m, err := trajectory.ParseMap(*mapping)
+	if err != nil {
+		panic(err)
+	}
+	result, err := m.Run(&i, trajectory.Limits{MaxSteps: *maxSteps, MaxBits: *maxBits})
+	if err != nil {
+		panic(err)
+	}
+	series := result.Series
 	for _, item := range series {
 		fmt.Printf("%v [", &item)
-		factors := factor(item)
-		for _, f := range factors {
-			fmt.Printf("%v, ", &f)
+		if item.Sign() != 0 {
+			abs := big.Int{}
+			factors, err := primes.Factor(abs.Abs(&item))
+			if err != nil {
+				panic(err)
+			}
+			for _, f := range factors {
+				fmt.Printf("%v, ", &f)
+			}
 		}
 		fmt.Printf("]\n")
 	}
+	switch result.Status {
+	case trajectory.StatusCycle:
+		fmt.Printf("%v [", result.Status)
+		for _, item := range result.Cycle {
+			fmt.Printf("%v, ", &item)
+		}
+		fmt.Printf("]\n")
+	case trajectory.StatusExceeded:
+		fmt.Println(result.Status)
+	}
 	sumProductTest(series)
 
-	found := make(map[string]bool)
+	found := trajectory.NewGraph()
+	found.Factor = *export != ""
 	j := big.Int{}
 	for i := 1; i < 1e3; i++ {
 		j.SetInt64(int64(i))
-		series := collatz(&j)
-		for _, item := range series {
-			found[item.String()] = true
+		series, err := trajectory.Collatz(&j)
+		if err != nil {
+			panic(err)
+		}
+		err = found.Add(series)
+		if err != nil {
+			panic(err)
 		}
 		count := 0
 		for k := 1; k < i; k++ {
 			j.SetInt64(int64(k))
-			if !found[j.String()] {
+			if !found.Contains(&j) {
 				count++
 			}
 		}
@@ -949,21 +1333,80 @@ func main() {
 			fmt.Println(i, count)
 		}
 	}
-	even()
-	odd()
+	if *export != "" {
+		exportGraph(found, *export)
+	}
+	ctx, stop := interruptible()
+	defer stop()
+	even(ctx)
+	if ctx.Err() == nil {
+		odd(ctx)
+	}
 }
 
-func even() {
-	histogram := make(map[int]int)
+// powerHistogram counts, with checkpoints, how many times 2 divides value(i) for i = start, start+2, ... < 2^30,
+// an interrupted count is saved and ctx.Err() is returned
+func powerHistogram(ctx context.Context, name string, start int, value func(i int) int) (map[int]int, *gohistogram.NumericHistogram, error) {
+	state := struct {
+		Next      int
+		Histogram map[int]int
+	}{Next: start, Histogram: make(map[int]int)}
+	loadCheckpoint(name, &state)
+
+	const block = 1 << 20
+	interval := checkpoint.NewInterval(*every)
+	for state.Next < 1024*1024*1024 {
+		if ctx.Err() != nil {
+			saveCheckpoint(name, &state)
+			fmt.Println("interrupted, saved", checkpointPath(name))
+			return nil, nil, ctx.Err()
+		}
+		end := state.Next + block
+		if end > 1024*1024*1024 {
+			end = 1024 * 1024 * 1024
+		}
+		var counts [64]int
+		for i := state.Next; i < end; i += 2 {
+			count, number := 0, value(i)
+			for number&1 == 0 {
+				count++
+				number >>= 1
+			}
+			counts[count]++
+		}
+		for count, value := range counts {
+			if value > 0 {
+				state.Histogram[count] += value
+			}
+		}
+		state.Next = end
+		if interval.Due() {
+			saveCheckpoint(name, &state)
+		}
+	}
+	removeCheckpoint(name)
+
+	// with fewer distinct counts than bins the histogram is exact and independent of insertion order
+	keys := make([]int, 0, len(state.Histogram))
+	for key := range state.Histogram {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
 	h := gohistogram.NewHistogram(100)
-	for i := 2; i < 1024*1024*1024; i += 2 {
-		count, number := 0, i
-		for number&1 == 0 {
-			count++
-			number >>= 1
+	for _, key := range keys {
+		for j := 0; j < state.Histogram[key]; j++ {
+			h.Add(float64(key))
 		}
-		histogram[count]++
-		h.Add(float64(count))
+	}
+	return state.Histogram, h, nil
+}
+
+func even(ctx context.Context) {
+	histogram, h, err := powerHistogram(ctx, "even", 2, func(i int) int {
+		return i
+	})
+	if err != nil {
+		return
 	}
 	fmt.Println(h.String())
 	max := 0
@@ -981,17 +1424,12 @@ func even() {
 	}
 }
 
-func odd() {
-	histogram := make(map[int]int)
-	h := gohistogram.NewHistogram(100)
-	for i := 1; i < 1024*1024*1024; i += 2 {
-		count, number := 0, 3*i+1
-		for number&1 == 0 {
-			count++
-			number >>= 1
-		}
-		histogram[count]++
-		h.Add(float64(count))
+func odd(ctx context.Context) {
+	histogram, h, err := powerHistogram(ctx, "odd", 1, func(i int) int {
+		return 3*i + 1
+	})
+	if err != nil {
+		return
 	}
 	fmt.Println(h.String())
 	max := 0
@@ -0,0 +1,156 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oeis
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pointlander/collatz/internal/atomicfile"
+)
+
+// StatusError is an unexpected HTTP status
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("oeis: %s: %s", e.URL, http.StatusText(e.StatusCode))
+}
+
+// temporary returns true if the request may succeed when retried
+func (e *StatusError) temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// DefaultTimeout bounds each attempt of a Fetcher without a Client, including reading the body
+const DefaultTimeout = 10 * time.Minute
+
+var defaultClient = &http.Client{Timeout: DefaultTimeout}
+
+// Fetcher downloads files only when they have changed, the ETag of each download is kept next
+// to the file in <path>.etag
+type Fetcher struct {
+	// Client is the HTTP client, nil means a client with DefaultTimeout
+	Client *http.Client
+	// Retries is the number of times a failed download is retried
+	Retries int
+	// Backoff is the delay before the first retry, it doubles after every retry
+	Backoff time.Duration
+}
+
+// NewFetcher creates a Fetcher with the default client that retries three times
+func NewFetcher() *Fetcher {
+	return &Fetcher{
+		Retries: 3,
+		Backoff: time.Second,
+	}
+}
+
+// Fetch downloads url to path unless the server reports it is not modified, it returns true if
+// path was replaced. The download is written to a temporary file that is verified and then
+// renamed over path so an interrupted download leaves the previous file intact. Retries stop when
+// ctx is done
+func (f *Fetcher) Fetch(ctx context.Context, url, path string) (bool, error) {
+	backoff := f.Backoff
+	for i := 0; ; i++ {
+		downloaded, err := f.fetch(ctx, url, path)
+		var status *StatusError
+		if err == nil || i >= f.Retries || ctx.Err() != nil || (errors.As(err, &status) && !status.temporary()) {
+			return downloaded, err
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+func (f *Fetcher) fetch(ctx context.Context, url, path string) (bool, error) {
+	client := f.Client
+	if client == nil {
+		client = defaultClient
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err
+	}
+	if stat, err := os.Stat(path); err == nil {
+		request.Header.Set("If-Modified-Since", stat.ModTime().UTC().Format(http.TimeFormat))
+		if etag, err := os.ReadFile(path + ".etag"); err == nil {
+			request.Header.Set("If-None-Match", strings.TrimSpace(string(etag)))
+		}
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return false, nil
+	default:
+		return false, &StatusError{URL: url, StatusCode: response.StatusCode}
+	}
+
+	err = atomicfile.Write(path, func(out *os.File) error {
+		if _, err := io.Copy(out, response.Body); err != nil {
+			return err
+		}
+		if strings.HasSuffix(path, ".gz") {
+			if err := verify(out); err != nil {
+				return err
+			}
+		}
+		if last, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+			return os.Chtimes(out.Name(), last, last)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	etag := response.Header.Get("ETag")
+	if etag == "" {
+		err = os.Remove(path + ".etag")
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+		return true, err
+	}
+	return true, os.WriteFile(path+".etag", []byte(etag+"\n"), 0644)
+}
+
+// verify checks that the gzip file in decompresses completely
+func verify(in *os.File) error {
+	if _, err := in.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	reader, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("oeis: corrupt download: %w", err)
+	}
+	defer reader.Close()
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return fmt.Errorf("oeis: corrupt download: %w", err)
+	}
+	return nil
+}
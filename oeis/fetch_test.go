@@ -0,0 +1,222 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oeis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serve starts a server with handler and returns it with the number of requests it has received
+func serve(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *int32) {
+	t.Helper()
+	requests := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// testFetcher retries quickly
+func testFetcher(server *httptest.Server) *Fetcher {
+	return &Fetcher{
+		Client:  server.Client(),
+		Retries: 3,
+		Backoff: time.Millisecond,
+	}
+}
+
+// checkFile checks the contents of path and that no temporary files were left next to it
+func checkFile(t *testing.T, path string, want []byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("%s = %q, want %q", path, data, want)
+	}
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) > 0 {
+		t.Errorf("temporary files were left: %v", matches)
+	}
+}
+
+func TestFetchETag(t *testing.T) {
+	data := compress(t, stripped)
+	server, requests := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write(data)
+	})
+	path := filepath.Join(t.TempDir(), "stripped.gz")
+	fetcher := testFetcher(server)
+
+	downloaded, err := fetcher.Fetch(context.Background(), server.URL, path)
+	if err != nil || !downloaded {
+		t.Fatalf("first Fetch = %v, %v", downloaded, err)
+	}
+	checkFile(t, path, data)
+	checkFile(t, path+".etag", []byte("\"v1\"\n"))
+
+	downloaded, err = fetcher.Fetch(context.Background(), server.URL, path)
+	if err != nil || downloaded {
+		t.Fatalf("second Fetch = %v, %v, want not modified", downloaded, err)
+	}
+	checkFile(t, path, data)
+	if *requests != 2 {
+		t.Errorf("%d requests, want 2", *requests)
+	}
+}
+
+func TestFetchIfModifiedSince(t *testing.T) {
+	data := compress(t, names)
+	modified := time.Date(2019, time.March, 1, 12, 0, 0, 0, time.UTC)
+	server, _ := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !modified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		w.Write(data)
+	})
+	path := filepath.Join(t.TempDir(), "names.gz")
+	fetcher := testFetcher(server)
+
+	downloaded, err := fetcher.Fetch(context.Background(), server.URL, path)
+	if err != nil || !downloaded {
+		t.Fatalf("first Fetch = %v, %v", downloaded, err)
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stat.ModTime().Equal(modified) {
+		t.Errorf("modification time = %v, want %v", stat.ModTime(), modified)
+	}
+	if _, err := os.Stat(path + ".etag"); !os.IsNotExist(err) {
+		t.Errorf("etag without an ETag header: %v", err)
+	}
+
+	downloaded, err = fetcher.Fetch(context.Background(), server.URL, path)
+	if err != nil || downloaded {
+		t.Fatalf("second Fetch = %v, %v, want not modified", downloaded, err)
+	}
+	checkFile(t, path, data)
+}
+
+func TestFetchRetries(t *testing.T) {
+	data := compress(t, stripped)
+	failures := int32(2)
+	server, requests := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(data)
+	})
+	path := filepath.Join(t.TempDir(), "stripped.gz")
+	downloaded, err := testFetcher(server).Fetch(context.Background(), server.URL, path)
+	if err != nil || !downloaded {
+		t.Fatalf("Fetch = %v, %v", downloaded, err)
+	}
+	checkFile(t, path, data)
+	if *requests != 3 {
+		t.Errorf("%d requests, want 3", *requests)
+	}
+}
+
+func TestFetchGivesUp(t *testing.T) {
+	server, requests := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "broken", http.StatusInternalServerError)
+	})
+	path := filepath.Join(t.TempDir(), "stripped.gz")
+	_, err := testFetcher(server).Fetch(context.Background(), server.URL, path)
+	var status *StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Fetch = %v, want %d", err, http.StatusInternalServerError)
+	}
+	if *requests != 4 {
+		t.Errorf("%d requests, want 4", *requests)
+	}
+}
+
+func TestFetchNotFound(t *testing.T) {
+	server, requests := serve(t, http.NotFound)
+	path := filepath.Join(t.TempDir(), "stripped.gz")
+	_, err := testFetcher(server).Fetch(context.Background(), server.URL, path)
+	var status *StatusError
+	if !errors.As(err, &status) || status.StatusCode != http.StatusNotFound {
+		t.Fatalf("Fetch = %v, want %d", err, http.StatusNotFound)
+	}
+	if *requests != 1 {
+		t.Errorf("%d requests, want 1 without retries", *requests)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("a failed download created %s", path)
+	}
+}
+
+func TestFetchCorrupt(t *testing.T) {
+	data := compress(t, stripped)
+	server, _ := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data[:len(data)/2])
+	})
+	path := filepath.Join(t.TempDir(), "stripped.gz")
+	old := compress(t, names)
+	if err := os.WriteFile(path, old, 0644); err != nil {
+		t.Fatal(err)
+	}
+	fetcher := testFetcher(server)
+	fetcher.Retries = 0
+	if _, err := fetcher.Fetch(context.Background(), server.URL, path); err == nil {
+		t.Fatal("Fetch of a truncated gzip file succeeded")
+	}
+	checkFile(t, path, old)
+}
+
+func TestFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server, _ := serve(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	defer close(release)
+	path := filepath.Join(t.TempDir(), "stripped.gz")
+
+	client := *server.Client()
+	client.Timeout = 50 * time.Millisecond
+	fetcher := testFetcher(server)
+	fetcher.Client, fetcher.Retries = &client, 0
+	if _, err := fetcher.Fetch(context.Background(), server.URL, path); err == nil {
+		t.Error("Fetch of a stalled download succeeded")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	fetcher = testFetcher(server)
+	if _, err := fetcher.Fetch(ctx, server.URL, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Fetch = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("a stalled download created %s", path)
+	}
+}
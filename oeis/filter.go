@@ -0,0 +1,122 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oeis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sign restricts the signs of the terms of a sequence
+type Sign int
+
+const (
+	// AnySign allows any terms
+	AnySign Sign = iota
+	// NonNegative skips sequences with negative terms
+	NonNegative
+	// Positive skips sequences with negative or zero terms
+	Positive
+)
+
+// Signs are the names of the sign filters
+var Signs = map[string]Sign{
+	"any":         AnySign,
+	"nonnegative": NonNegative,
+	"positive":    Positive,
+}
+
+// ParseSign returns the sign filter with the given name
+func ParseSign(name string) (Sign, error) {
+	sign, ok := Signs[name]
+	if !ok {
+		return AnySign, fmt.Errorf("oeis: unknown sign filter %q", name)
+	}
+	return sign, nil
+}
+
+// Number returns the number of an A-number, 45 for A000045
+func Number(name string) (int, error) {
+	if !valid(name) {
+		return 0, fmt.Errorf("oeis: %w %q", ErrName, name)
+	}
+	return strconv.Atoi(name[1:])
+}
+
+// ParseRange parses a range of A-numbers such as "A000001-A010000", either end may be left out
+func ParseRange(s string) (first, last int, err error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("oeis: invalid range %q", s)
+	}
+	if from != "" {
+		if first, err = Number(from); err != nil {
+			return 0, 0, err
+		}
+	}
+	if to != "" {
+		if last, err = Number(to); err != nil {
+			return 0, 0, err
+		}
+		if last < first {
+			return 0, 0, fmt.Errorf("oeis: empty range %q", s)
+		}
+	}
+	return first, last, nil
+}
+
+// Filter selects records, the zero Filter selects every record
+type Filter struct {
+	// MinTerms and MaxTerms bound the number of terms, zero means no bound
+	MinTerms, MaxTerms int
+	Sign               Sign
+	// Monotonic only selects sequences that never decrease or never increase
+	Monotonic bool
+	// First and Last bound the numbers of the A-numbers, zero means no bound
+	First, Last int
+}
+
+// MatchName returns true if the filter selects the A-number name, the terms are not checked
+func (f Filter) MatchName(name string) bool {
+	if f.First > 0 || f.Last > 0 {
+		number, err := Number(name)
+		if err != nil || number < f.First || (f.Last > 0 && number > f.Last) {
+			return false
+		}
+	}
+	return true
+}
+
+// Match returns true if the filter selects the record
+func (f Filter) Match(r Record) bool {
+	if len(r.Terms) < f.MinTerms || (f.MaxTerms > 0 && len(r.Terms) > f.MaxTerms) {
+		return false
+	}
+	if !f.MatchName(r.Name) {
+		return false
+	}
+	for i := range r.Terms {
+		sign := r.Terms[i].Sign()
+		if (f.Sign == NonNegative && sign < 0) || (f.Sign == Positive && sign <= 0) {
+			return false
+		}
+	}
+	if f.Monotonic {
+		increasing, decreasing := true, true
+		for i := 1; i < len(r.Terms); i++ {
+			switch r.Terms[i].Cmp(&r.Terms[i-1]) {
+			case -1:
+				increasing = false
+			case 1:
+				decreasing = false
+			}
+		}
+		if !increasing && !decreasing {
+			return false
+		}
+	}
+	return true
+}
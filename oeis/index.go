@@ -0,0 +1,184 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oeis
+
+import (
+	"errors"
+	"hash/fnv"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// DefaultWindow is the default number of consecutive terms hashed by an Index
+const DefaultWindow = 6
+
+// posting is a window of a sequence
+type posting struct {
+	hash   uint64
+	record int32
+	offset int32
+}
+
+// Index finds the sequences that contain a series of consecutive terms. Terms are compared by
+// their 64 bit hashes and windows of consecutive terms are looked up in a sorted table, every
+// candidate is then confirmed against the decimal terms so hash collisions are never reported
+type Index struct {
+	// Window is the number of consecutive terms in each window
+	Window int
+
+	names []string
+	terms [][]uint64
+	// texts are the comma separated decimal terms of each sequence
+	texts    []string
+	postings []posting
+}
+
+// Match is a sequence that contains a series
+type Match struct {
+	Name string
+	// Offset is the index of the term where the series starts
+	Offset int
+	// Partial is set when the stripped terms of the sequence end before the series does, at least
+	// Window terms of the series matched
+	Partial bool
+}
+
+// Prefix returns true if the series is a prefix of the sequence
+func (m Match) Prefix() bool {
+	return m.Offset == 0
+}
+
+// hash hashes a term
+func hash(x *big.Int) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte{byte(x.Sign() + 1)})
+	h.Write(x.Bytes())
+	return h.Sum64()
+}
+
+// window hashes consecutive term hashes
+func window(terms []uint64) uint64 {
+	h := uint64(14695981039346656037)
+	for _, t := range terms {
+		h = (h ^ t) * 1099511628211
+	}
+	return h
+}
+
+// NewIndex indexes every record from reader with windows of size terms
+func NewIndex(reader *Reader, size int) (*Index, error) {
+	if size < 1 {
+		return nil, errors.New("oeis: window must be at least one term")
+	}
+	index := &Index{Window: size}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		terms := make([]uint64, len(record.Terms))
+		for i := range record.Terms {
+			terms[i] = hash(&record.Terms[i])
+		}
+		id := int32(len(index.names))
+		index.names = append(index.names, record.Name)
+		index.terms = append(index.terms, terms)
+		index.texts = append(index.texts, strings.Join(record.Strings(), ","))
+		for i := 0; i+size <= len(terms); i++ {
+			index.postings = append(index.postings, posting{hash: window(terms[i : i+size]), record: id, offset: int32(i)})
+		}
+	}
+	sort.Slice(index.postings, func(i, j int) bool {
+		return index.postings[i].hash < index.postings[j].hash
+	})
+	return index, nil
+}
+
+// Len returns the number of indexed sequences
+func (x *Index) Len() int {
+	return len(x.names)
+}
+
+// Lookup returns the sequences that contain series as consecutive terms in order of A-number and
+// offset, series shorter than the window are found by scanning every sequence
+func (x *Index) Lookup(series []big.Int) []Match {
+	if len(series) == 0 {
+		return nil
+	}
+	terms, texts := make([]uint64, len(series)), make([]string, len(series))
+	for i := range series {
+		terms[i], texts[i] = hash(&series[i]), series[i].String()
+	}
+
+	var matches []Match
+	if len(terms) < x.Window {
+		for record, sequence := range x.terms {
+			for offset := 0; offset+len(terms) <= len(sequence); offset++ {
+				if equal(sequence[offset:offset+len(terms)], terms) && x.confirm(record, offset, texts) {
+					matches = append(matches, Match{Name: x.names[record], Offset: offset})
+				}
+			}
+		}
+	} else {
+		matches = x.windows(terms, texts)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Name != matches[j].Name {
+			return matches[i].Name < matches[j].Name
+		}
+		return matches[i].Offset < matches[j].Offset
+	})
+	return matches
+}
+
+// windows returns the matches of terms that are at least Window long from the postings of its
+// first window
+func (x *Index) windows(terms []uint64, texts []string) []Match {
+	var matches []Match
+	h := window(terms[:x.Window])
+	first := sort.Search(len(x.postings), func(i int) bool {
+		return x.postings[i].hash >= h
+	})
+	for _, p := range x.postings[first:] {
+		if p.hash != h {
+			break
+		}
+		sequence, offset := x.terms[p.record], int(p.offset)
+		end := offset + len(terms)
+		partial := end > len(sequence)
+		if partial {
+			end = len(sequence)
+		}
+		if equal(sequence[offset:end], terms[:end-offset]) && x.confirm(int(p.record), offset, texts) {
+			matches = append(matches, Match{Name: x.names[p.record], Offset: offset, Partial: partial})
+		}
+	}
+	return matches
+}
+
+// confirm returns true if the decimal terms of record from offset on match texts for as long as
+// the record has terms
+func (x *Index) confirm(record, offset int, texts []string) bool {
+	sequence := strings.Split(x.texts[record], ",")[offset:]
+	for i := 0; i < len(sequence) && i < len(texts); i++ {
+		if sequence[i] != texts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equal(a, b []uint64) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
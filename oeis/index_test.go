@@ -0,0 +1,83 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oeis
+
+import (
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// index indexes the fixture stripped file
+func index(t *testing.T, window int) *Index {
+	t.Helper()
+	index, err := NewIndex(NewReader(strings.NewReader(stripped)), window)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+func series(values ...int64) []big.Int {
+	terms := make([]big.Int, len(values))
+	for i, v := range values {
+		terms[i].SetInt64(v)
+	}
+	return terms
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		series []big.Int
+		want   []Match
+	}{
+		{series(1, 2, 3, 4), []Match{{Name: "A000027"}}},
+		{series(2, 3, 5, 8, 13, 21), []Match{{Name: "A000045", Offset: 3}}},
+		{series(1, 2), []Match{{Name: "A000027"}, {Name: "A000045", Offset: 2}, {Name: "A000079"}}},
+		{series(5, 6, 7, 8, 9, 10, 11, 12), []Match{{Name: "A000027", Offset: 4, Partial: true}}},
+		{series(2, 4, 8, 16, 32, 64, 128, 256), []Match{{Name: "A000079", Offset: 1, Partial: true}}},
+		{series(1, 2, 4, 7), nil},
+	}
+	for _, window := range []int{1, 3, DefaultWindow} {
+		index := index(t, window)
+		if index.Len() != 3 {
+			t.Fatalf("Len = %d, want 3", index.Len())
+		}
+		for _, test := range tests {
+			if got := index.Lookup(test.series); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("window %d: Lookup(%v) = %v, want %v", window, test.series, got, test.want)
+			}
+		}
+	}
+}
+
+// TestLookupCollision replaces the term hashes of a sequence with those of a different series so
+// that every hash matches while the terms do not
+func TestLookupCollision(t *testing.T) {
+	for _, size := range []int{3, DefaultWindow} {
+		x := index(t, size)
+		query := series(1, 3, 5, 7, 9, 11, 13, 17, 19)
+		hashes := make([]uint64, len(query))
+		for i := range query {
+			hashes[i] = hash(&query[i])
+		}
+		// A000027 has 10 terms so every window of its forged hashes is a posting
+		x.terms[0] = append(hashes, hash(big.NewInt(23)))
+		for i := 0; i+size <= len(x.terms[0]); i++ {
+			x.postings = append(x.postings, posting{hash: window(x.terms[0][i : i+size]), record: 0, offset: int32(i)})
+		}
+		sort.Slice(x.postings, func(i, j int) bool {
+			return x.postings[i].hash < x.postings[j].hash
+		})
+		if got := x.Lookup(query); len(got) != 0 {
+			t.Errorf("window %d: Lookup of colliding hashes = %v, want no matches", size, got)
+		}
+		if got := x.Lookup(query[:2]); len(got) != 0 {
+			t.Errorf("window %d: short Lookup of colliding hashes = %v, want no matches", size, got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oeis
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Mirror is a directory holding stripped.gz, names.gz and b-files named like b000045.txt
+type Mirror struct {
+	Dir string
+}
+
+// compressed is a decompressed file that closes the file with the decompressor
+type compressed struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (c compressed) Close() error {
+	err := c.Reader.Close()
+	if err := c.file.Close(); err != nil {
+		return err
+	}
+	return err
+}
+
+// open opens a gzip compressed file of the mirror
+func (m Mirror) open(name string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(m.Dir, name))
+	if err != nil {
+		return nil, err
+	}
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("oeis: %s: %w", name, err)
+	}
+	return compressed{Reader: reader, file: file}, nil
+}
+
+// Stripped opens the decompressed stripped file of the mirror
+func (m Mirror) Stripped() (io.ReadCloser, error) {
+	return m.open("stripped.gz")
+}
+
+// Names reads the names file of the mirror
+func (m Mirror) Names() (map[string]string, error) {
+	in, err := m.open("names.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return ReadNames(in)
+}
+
+// BFile reads the b-file of the sequence name, the error satisfies os.IsNotExist when the mirror
+// does not have it
+func (m Mirror) BFile(name string) ([]big.Int, error) {
+	if !valid(name) {
+		return nil, fmt.Errorf("oeis: %w %q", ErrName, name)
+	}
+	in, err := os.Open(filepath.Join(m.Dir, "b"+name[1:]+".txt"))
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+	return ReadBFile(in)
+}
+
+// ReadNames reads a names file, "A000045 Fibonacci numbers", into a map from A-number to name
+func ReadNames(r io.Reader) (map[string]string, error) {
+	names, reader, line := make(map[string]string), bufio.NewReader(r), 0
+	for {
+		s, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return names, err
+		}
+		if err == io.EOF && s == "" {
+			return names, nil
+		}
+		line++
+		s = strings.TrimRight(s, "\r\n")
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		name, description, ok := strings.Cut(s, " ")
+		if !ok || !valid(name) {
+			return names, &ParseError{Line: line, Err: ErrFormat}
+		}
+		names[name] = description
+	}
+}
+
+// ReadBFile reads the terms of a b-file, lines of an index followed by a term
+func ReadBFile(r io.Reader) ([]big.Int, error) {
+	var terms []big.Int
+	reader, line := bufio.NewReader(r), 0
+	for {
+		s, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return terms, err
+		}
+		if err == io.EOF && s == "" {
+			return terms, nil
+		}
+		line++
+		s = strings.TrimSpace(s)
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		fields := strings.Fields(s)
+		if len(fields) < 2 {
+			return terms, &ParseError{Line: line, Err: ErrFormat}
+		}
+		terms = append(terms, big.Int{})
+		if _, ok := terms[len(terms)-1].SetString(fields[1], 10); !ok {
+			return terms[:len(terms)-1], &ParseError{Line: line, Err: fmt.Errorf("%w %q", ErrTerm, fields[1])}
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oeis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	stripped = `# OEIS stripped file
+# Last Modified: today
+
+A000027 ,1,2,3,4,5,6,7,8,9,10,
+A000045 ,0,1,1,2,3,5,8,13,21,34,
+A000079 ,1,2,4,8,16,32,64,128,
+`
+	names = `# OEIS names file
+
+A000027 The positive integers.
+A000045 Fibonacci numbers: F(n) = F(n-1) + F(n-2) with F(0) = 0 and F(1) = 1.
+A000079 Powers of 2: a(n) = 2^n.
+`
+	bfile = `# A000045 b-file
+0 0
+1 1
+2 1
+3 2
+4 3
+5 5
+6 8
+7 13
+8 21
+9 34
+10 55
+11 89
+`
+)
+
+// compress returns s compressed with gzip
+func compress(t testing.TB, s string) []byte {
+	t.Helper()
+	buffer := bytes.Buffer{}
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+// fixture writes a small mirror to a temporary directory
+func fixture(t *testing.T) Mirror {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"stripped.gz": compress(t, stripped),
+		"names.gz":    compress(t, names),
+		"b000045.txt": []byte(bfile),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return Mirror{Dir: dir}
+}
+
+// checkMirror reads every file of a mirror holding the fixture
+func checkMirror(t *testing.T, mirror Mirror) {
+	t.Helper()
+	names, err := mirror.Names()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 3 || names["A000045"] != "Fibonacci numbers: F(n) = F(n-1) + F(n-2) with F(0) = 0 and F(1) = 1." {
+		t.Errorf("Names = %v", names)
+	}
+
+	in, err := mirror.Stripped()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	reader := NewReader(in)
+	var got []string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, record.Name)
+		if record.Name == "A000079" {
+			if want := []string{"1", "2", "4", "8", "16", "32", "64", "128"}; !reflect.DeepEqual(record.Strings(), want) {
+				t.Errorf("A000079 = %v, want %v", record.Strings(), want)
+			}
+		}
+	}
+	if want := []string{"A000027", "A000045", "A000079"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %v, want %v", got, want)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	mirror := fixture(t)
+	checkMirror(t, mirror)
+
+	terms, err := mirror.BFile("A000045")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(terms) != 12 || terms[11].Int64() != 89 {
+		t.Errorf("BFile(A000045) = %v", terms)
+	}
+	if _, err := mirror.BFile("A000027"); !os.IsNotExist(err) {
+		t.Errorf("BFile(A000027) = %v, want a missing file", err)
+	}
+	if _, err := mirror.BFile("b000045"); !errors.Is(err, ErrName) {
+		t.Errorf("BFile(b000045) = %v, want %v", err, ErrName)
+	}
+	if _, err := (Mirror{Dir: t.TempDir()}).Names(); !os.IsNotExist(err) {
+		t.Errorf("Names of an empty mirror = %v, want a missing file", err)
+	}
+}
+
+func TestReadBFile(t *testing.T) {
+	_, err := ReadBFile(bytes.NewBufferString("0 1\n1\n"))
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 2 || !errors.Is(err, ErrFormat) {
+		t.Errorf("ReadBFile = %v, want a format error on line 2", err)
+	}
+	_, err = ReadBFile(bytes.NewBufferString("0 1\n1 x\n"))
+	if !errors.As(err, &parseErr) || parseErr.Line != 2 || !errors.Is(err, ErrTerm) {
+		t.Errorf("ReadBFile = %v, want a term error on line 2", err)
+	}
+}
+
+func TestMirrorFetch(t *testing.T) {
+	files := map[string][]byte{
+		"/stripped.gz": compress(t, stripped),
+		"/names.gz":    compress(t, names),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	mirror := Mirror{Dir: t.TempDir()}
+	fetcher := &Fetcher{Client: server.Client()}
+	for _, name := range []string{"stripped.gz", "names.gz"} {
+		downloaded, err := fetcher.Fetch(context.Background(), server.URL+"/"+name, filepath.Join(mirror.Dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !downloaded {
+			t.Errorf("%s was not downloaded", name)
+		}
+	}
+	checkMirror(t, mirror)
+}
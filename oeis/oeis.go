@@ -0,0 +1,141 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package oeis reads sequences from the On-Line Encyclopedia of Integer Sequences.
+package oeis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// Record is a sequence from the stripped file
+type Record struct {
+	// Name is the A-number of the sequence such as A000045
+	Name  string
+	Terms []big.Int
+}
+
+// Strings returns the terms of the record in decimal
+func (r Record) Strings() []string {
+	terms := make([]string, len(r.Terms))
+	for i := range r.Terms {
+		terms[i] = r.Terms[i].String()
+	}
+	return terms
+}
+
+// ParseError is a malformed line of the stripped file
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("oeis: line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// ErrFormat is a line that is missing its A-number or its terms
+	ErrFormat = errors.New("malformed line")
+	// ErrName is an invalid A-number
+	ErrName = errors.New("invalid A-number")
+	// ErrTerm is a term that is not an integer
+	ErrTerm = errors.New("invalid term")
+)
+
+// Reader streams the records of the stripped file, "A000045 ,0,1,1,2,3,5,", skipping
+// comments and blank lines
+type Reader struct {
+	// SkipBad skips malformed records instead of returning a ParseError
+	SkipBad bool
+	// Skipped is the number of malformed records skipped
+	Skipped int
+
+	reader *bufio.Reader
+	line   int
+}
+
+// NewReader creates a Reader from r, which must already be decompressed
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		reader: bufio.NewReader(r),
+	}
+}
+
+// Line returns the number of the last line read
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Read returns the next record or io.EOF when there are no more records
+func (r *Reader) Read() (Record, error) {
+	for {
+		s, err := r.reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return Record{}, err
+		}
+		if err == io.EOF && s == "" {
+			return Record{}, io.EOF
+		}
+		r.line++
+		s = strings.TrimRight(s, "\r\n")
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		record, parseErr := parse(s)
+		if parseErr != nil {
+			if r.SkipBad {
+				r.Skipped++
+				continue
+			}
+			return Record{}, &ParseError{Line: r.line, Err: parseErr}
+		}
+		return record, nil
+	}
+}
+
+// parse parses a single line of the stripped file
+func parse(s string) (Record, error) {
+	name, terms, ok := strings.Cut(s, " ")
+	if !ok {
+		return Record{}, ErrFormat
+	}
+	if !valid(name) {
+		return Record{}, fmt.Errorf("%w %q", ErrName, name)
+	}
+	terms = strings.Trim(strings.TrimSpace(terms), ",")
+	if terms == "" {
+		return Record{}, ErrFormat
+	}
+	parts := strings.Split(terms, ",")
+	record := Record{Name: name, Terms: make([]big.Int, len(parts))}
+	for i, part := range parts {
+		if _, ok := record.Terms[i].SetString(part, 10); !ok {
+			return Record{}, fmt.Errorf("%w %q in %s", ErrTerm, part, name)
+		}
+	}
+	return record, nil
+}
+
+// valid returns true if name is an A followed by six or more digits
+func valid(name string) bool {
+	if len(name) < 7 || name[0] != 'A' {
+		return false
+	}
+	for _, c := range name[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package oeis
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		skipBad bool
+		// records are the records read before the error, as a name followed by its terms
+		records [][]string
+		line    int
+		err     error
+		skipped int
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:    "comments and blank lines",
+			input:   "# OEIS\n\n#\n\nA000001 ,1,2,\n\n# A000002 ,3,\n",
+			records: [][]string{{"A000001", "1", "2"}},
+			line:    7,
+		},
+		{
+			name:    "no trailing newline",
+			input:   "A000001 ,1,2,\nA000002 ,-3,4",
+			records: [][]string{{"A000001", "1", "2"}, {"A000002", "-3", "4"}},
+			line:    2,
+		},
+		{
+			name:    "carriage returns",
+			input:   "# comment\r\n\r\nA000001 ,1,2,\r\n",
+			records: [][]string{{"A000001", "1", "2"}},
+			line:    3,
+		},
+		{
+			name:    "big terms",
+			input:   "A000001 ,340282366920938463463374607431768211456,\n",
+			records: [][]string{{"A000001", "340282366920938463463374607431768211456"}},
+			line:    1,
+		},
+		{
+			name:    "missing terms",
+			input:   "# comment\nA000001 ,1,\n\nA000002\nA000003 ,3,\n",
+			records: [][]string{{"A000001", "1"}},
+			line:    4,
+			err:     ErrFormat,
+		},
+		{
+			name:  "empty terms",
+			input: "A000001 ,,\n",
+			line:  1,
+			err:   ErrFormat,
+		},
+		{
+			name:  "bad name",
+			input: "\n\nB000001 ,1,\n",
+			line:  3,
+			err:   ErrName,
+		},
+		{
+			name:    "bad term",
+			input:   "A000001 ,1,\nA000002 ,1,x,3,",
+			records: [][]string{{"A000001", "1"}},
+			line:    2,
+			err:     ErrTerm,
+		},
+		{
+			name:    "skip bad",
+			input:   "A000001 ,1,\nA000002\nB000003 ,3,\n# comment\nA000004 ,4,x,\nA000005 ,5,",
+			skipBad: true,
+			records: [][]string{{"A000001", "1"}, {"A000005", "5"}},
+			line:    6,
+			skipped: 3,
+		},
+	}
+	for _, test := range tests {
+		reader := NewReader(strings.NewReader(test.input))
+		reader.SkipBad = test.skipBad
+		var records [][]string
+		var err error
+		for {
+			var record Record
+			record, err = reader.Read()
+			if err != nil {
+				break
+			}
+			records = append(records, append([]string{record.Name}, record.Strings()...))
+		}
+		if !reflect.DeepEqual(records, test.records) {
+			t.Errorf("%s: records = %v, want %v", test.name, records, test.records)
+		}
+		if test.err == nil {
+			if err != io.EOF {
+				t.Errorf("%s: error = %v, want %v", test.name, err, io.EOF)
+			}
+			if reader.Line() != test.line {
+				t.Errorf("%s: Line = %d, want %d", test.name, reader.Line(), test.line)
+			}
+		} else {
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) || !errors.Is(err, test.err) || parseErr.Line != test.line {
+				t.Errorf("%s: error = %v, want %v on line %d", test.name, err, test.err, test.line)
+			}
+		}
+		if reader.Skipped != test.skipped {
+			t.Errorf("%s: Skipped = %d, want %d", test.name, reader.Skipped, test.skipped)
+		}
+	}
+}
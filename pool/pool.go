@@ -0,0 +1,180 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package pool runs jobs on a bounded number of workers with cancellation.
+package pool
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Config configures Run
+type Config struct {
+	// Workers is the number of workers, zero means runtime.NumCPU()
+	Workers int
+	// Ordered collects results in the order the jobs were produced instead of as they finish,
+	// results after a missing one are dropped when the pool stops early. At most 2*Workers jobs
+	// are started ahead of the oldest uncollected one so a slow job holds up the others
+	Ordered bool
+	// OnError is called from the worker with a job that failed or panicked, the job is skipped
+	// if it returns nil, nil means every failed job stops the pool
+	OnError func(job interface{}, err error) error
+}
+
+// PanicError is a panic recovered from a job
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("pool: job panicked: %v\n%s", e.Value, e.Stack)
+}
+
+// item is a job or a result with the sequence number of its job
+type item struct {
+	seq   int
+	value interface{}
+	err   error
+	// skip is a failed job that is not collected
+	skip bool
+}
+
+// Run gets jobs from next until it returns false, runs work on them and passes the results to
+// collect on the calling goroutine. Jobs are only taken from next as workers become free. When
+// ctx is done, or next, work or collect return an error, or work panics, no more jobs are
+// started, the results of the jobs already started are still collected and the first error or
+// ctx.Err() is returned
+func Run(ctx context.Context, config Config,
+	next func() (job interface{}, ok bool, err error),
+	work func(job interface{}) (interface{}, error),
+	collect func(result interface{}) error) error {
+	workers := config.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	stop, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	var first error
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+
+	jobs, results := make(chan item, workers), make(chan item, workers)
+	// window holds a token for every ordered job that is started and not yet collected
+	var window chan struct{}
+	if config.Ordered {
+		window = make(chan struct{}, 2*workers)
+	}
+	go func() {
+		defer close(jobs)
+		for seq := 0; stop.Err() == nil; seq++ {
+			if window != nil {
+				select {
+				case window <- struct{}{}:
+				case <-stop.Done():
+					return
+				}
+			}
+			job, ok, err := next()
+			if err != nil {
+				fail(err)
+				return
+			}
+			if !ok {
+				return
+			}
+			select {
+			case jobs <- item{seq: seq, value: job}:
+			case <-stop.Done():
+				return
+			}
+		}
+	}()
+
+	var group sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for job := range jobs {
+				if stop.Err() != nil {
+					continue
+				}
+				result, err := safely(work, job.value)
+				skip := false
+				if err != nil && config.OnError != nil {
+					err = config.OnError(job.value, err)
+					skip = err == nil
+				}
+				results <- item{seq: job.seq, value: result, err: err, skip: skip}
+			}
+		}()
+	}
+	go func() {
+		group.Wait()
+		close(results)
+	}()
+
+	// collecting is false once collect has failed
+	pending, seq, collecting := make(map[int]item), 0, true
+	deliver := func(value interface{}) {
+		if !collecting {
+			return
+		}
+		if err := collect(value); err != nil {
+			collecting = false
+			fail(err)
+		}
+	}
+	for result := range results {
+		if result.err != nil {
+			fail(result.err)
+			continue
+		}
+		if !config.Ordered {
+			if !result.skip {
+				deliver(result.value)
+			}
+			continue
+		}
+		pending[result.seq] = result
+		for {
+			result, ok := pending[seq]
+			if !ok {
+				break
+			}
+			delete(pending, seq)
+			seq++
+			<-window
+			if !result.skip {
+				deliver(result.value)
+			}
+		}
+	}
+
+	if first != nil {
+		return first
+	}
+	return ctx.Err()
+}
+
+// safely runs work on job and returns a panic as a PanicError
+func safely(work func(job interface{}) (interface{}, error), job interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return work(job)
+}
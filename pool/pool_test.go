@@ -0,0 +1,178 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package pool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// count returns a next function for the jobs 0, 1, ... n-1, n < 0 never runs out
+func count(n int) func() (interface{}, bool, error) {
+	i := 0
+	return func() (interface{}, bool, error) {
+		i++
+		return i - 1, n < 0 || i <= n, nil
+	}
+}
+
+func TestOrder(t *testing.T) {
+	const jobs = 100
+	// later jobs finish first
+	work := func(job interface{}) (interface{}, error) {
+		time.Sleep(time.Duration(jobs-job.(int)) * 10 * time.Microsecond)
+		return 2 * job.(int), nil
+	}
+	want := make([]int, jobs)
+	for i := range want {
+		want[i] = 2 * i
+	}
+	for _, ordered := range []bool{false, true} {
+		var got []int
+		err := Run(context.Background(), Config{Workers: 8, Ordered: ordered}, count(jobs), work, func(result interface{}) error {
+			got = append(got, result.(int))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ordered {
+			sort.Ints(got)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ordered %v: results = %v, want %v", ordered, got, want)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	const workers = 4
+	started, collected, max := 0, int32(0), 0
+	var mutex sync.Mutex
+	next := func() (interface{}, bool, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if ahead := started - int(atomic.LoadInt32(&collected)); ahead > max {
+			max = ahead
+		}
+		started++
+		return started - 1, started <= 200, nil
+	}
+	// the first job of every 50 is slow so the others pile up behind it
+	work := func(job interface{}) (interface{}, error) {
+		if job.(int)%50 == 0 {
+			time.Sleep(20 * time.Millisecond)
+		}
+		return job, nil
+	}
+	seq := 0
+	err := Run(context.Background(), Config{Workers: workers, Ordered: true}, next, work, func(result interface{}) error {
+		if result.(int) != seq {
+			t.Fatalf("result %v, want %d", result, seq)
+		}
+		seq++
+		atomic.AddInt32(&collected, 1)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 200 {
+		t.Errorf("%d results, want 200", seq)
+	}
+	if max > 2*workers {
+		t.Errorf("%d jobs were started ahead of the collected results, want at most %d", max, 2*workers)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	work := func(job interface{}) (interface{}, error) {
+		if job.(int) == 3 {
+			panic("three")
+		}
+		if job.(int) == 5 {
+			return nil, errors.New("five")
+		}
+		return job, nil
+	}
+	for _, ordered := range []bool{false, true} {
+		var failed []interface{}
+		var mutex sync.Mutex
+		config := Config{
+			Workers: 3,
+			Ordered: ordered,
+			OnError: func(job interface{}, err error) error {
+				mutex.Lock()
+				defer mutex.Unlock()
+				failed = append(failed, job)
+				var panicErr *PanicError
+				if job.(int) == 3 && (!errors.As(err, &panicErr) || panicErr.Value != "three" || len(panicErr.Stack) == 0) {
+					t.Errorf("OnError(3, %v), want a PanicError", err)
+				}
+				return nil
+			},
+		}
+		var got []int
+		err := Run(context.Background(), config, count(8), work, func(result interface{}) error {
+			got = append(got, result.(int))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Ints(got)
+		if want := []int{0, 1, 2, 4, 6, 7}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ordered %v: results = %v, want %v", ordered, got, want)
+		}
+		if len(failed) != 2 {
+			t.Errorf("ordered %v: OnError called with %v, want 3 and 5", ordered, failed)
+		}
+	}
+
+	err := Run(context.Background(), Config{Workers: 1}, count(8), work, func(interface{}) error {
+		return nil
+	})
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Errorf("Run = %v, want a PanicError", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	const workers = 4
+	for _, ordered := range []bool{false, true} {
+		ctx, cancel := context.WithCancel(context.Background())
+		release, running := make(chan struct{}), int32(0)
+		work := func(job interface{}) (interface{}, error) {
+			if atomic.AddInt32(&running, 1) == workers {
+				cancel()
+				close(release)
+			}
+			<-release
+			return job, nil
+		}
+		var got []int
+		err := Run(ctx, Config{Workers: workers, Ordered: ordered}, count(-1), work, func(result interface{}) error {
+			got = append(got, result.(int))
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("ordered %v: Run = %v, want %v", ordered, err, context.Canceled)
+		}
+		sort.Ints(got)
+		if want := []int{0, 1, 2, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("ordered %v: results = %v, want the %d started jobs", ordered, got, workers)
+		}
+		if running != workers {
+			t.Errorf("ordered %v: %d jobs were started after the cancellation", ordered, running-workers)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package primes generates and factors prime numbers.
+package primes
+
+import (
+	"errors"
+	"math/big"
+	"math/rand"
+)
+
+var (
+	zero = big.NewInt(0)
+	two  = big.NewInt(2)
+)
+
+// ErrNotPositive is returned when factoring a number less than one
+var ErrNotPositive = errors.New("primes: number must be positive")
+
+// Sieve returns the primes less than n using the sieve of Eratosthenes
+func Sieve(n uint64) (primes []uint64) {
+	b := make([]bool, n)
+	for i := uint64(2); i < n; i++ {
+		if b[i] {
+			continue
+		}
+		primes = append(primes, i)
+		for j := i * i; j < n; j += i {
+			b[j] = true
+		}
+	}
+	return
+}
+
+// Factor returns the prime factors of a in ascending order
+func Factor(a *big.Int) ([]big.Int, error) {
+	if a.Sign() <= 0 {
+		return nil, ErrNotPositive
+	}
+	number, primes, x := big.Int{}, make([]big.Int, 0, 256), big.Int{}
+	number.Set(a)
+
+	for x.Mod(&number, two).Cmp(zero) == 0 {
+		primes = append(primes, *two)
+		number.Div(&number, two)
+	}
+
+	i := big.NewInt(3)
+	for x.Mul(i, i).Cmp(&number) <= 0 {
+		for x.Mod(&number, i).Cmp(zero) == 0 {
+			y := big.Int{}
+			y.Set(i)
+			primes = append(primes, y)
+			number.Div(&number, i)
+		}
+		i.Add(i, two)
+	}
+
+	if number.Cmp(two) > 0 {
+		y := big.Int{}
+		y.Set(&number)
+		primes = append(primes, y)
+	}
+
+	return primes, nil
+}
+
+// Source is a source of pairs of primes
+type Source interface {
+	Next() (x, y uint64)
+	More() bool
+}
+
+// SequentialSource yields consecutive pairs of primes
+type SequentialSource struct {
+	Primes []uint64
+	I      int
+}
+
+// NewSequentialSource creates a SequentialSource over the primes less than max
+func NewSequentialSource(max uint64) *SequentialSource {
+	primes := Sieve(max)
+	return &SequentialSource{
+		Primes: primes,
+	}
+}
+
+// Next returns the next pair of consecutive primes
+func (s *SequentialSource) Next() (x, y uint64) {
+	x, y = s.Primes[s.I], s.Primes[s.I+1]
+	s.I++
+	return
+}
+
+// More returns true if there are more pairs
+func (s *SequentialSource) More() bool {
+	return s.I < len(s.Primes)-1
+}
+
+// RandomSource yields random pairs of primes
+type RandomSource struct {
+	Primes []uint64
+	I      int
+}
+
+// NewRandomSource creates a RandomSource over the primes less than max
+func NewRandomSource(max uint64) *RandomSource {
+	primes := Sieve(max)
+	return &RandomSource{
+		Primes: primes,
+	}
+}
+
+// Next returns a random pair of primes
+func (r *RandomSource) Next() (x, y uint64) {
+	length := len(r.Primes)
+	x, y = r.Primes[rand.Intn(length)], r.Primes[rand.Intn(length)]
+	r.I++
+	return
+}
+
+// More returns true if there are more pairs
+func (r *RandomSource) More() bool {
+	return r.I < len(r.Primes)-1
+}
@@ -0,0 +1,73 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// before returns true if a ranks before b, lower scores first and then by name
+func before(a, b *Row) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return a.Name < b.Name
+}
+
+// worst is a heap with the worst ranked sequence on top
+type worst []Row
+
+func (w worst) Len() int            { return len(w) }
+func (w worst) Less(i, j int) bool  { return before(&w[j], &w[i]) }
+func (w worst) Swap(i, j int)       { w[i], w[j] = w[j], w[i] }
+func (w *worst) Push(x interface{}) { *w = append(*w, x.(Row)) }
+func (w *worst) Pop() interface{} {
+	old := *w
+	x := old[len(old)-1]
+	*w = old[:len(old)-1]
+	return x
+}
+
+// Ranking keeps the best Size rows by lowest score, ties are broken by name so that the ranking
+// does not depend on the order the rows are added in
+type Ranking struct {
+	Size  int
+	worst worst
+}
+
+// NewRanking creates an empty Ranking of the best size sequences
+func NewRanking(size int) *Ranking {
+	return &Ranking{
+		Size: size,
+	}
+}
+
+// Add ranks a sequence, it is dropped if the ranking is full and it is not better than the worst
+// or if its score is not a number
+func (r *Ranking) Add(x Row) {
+	if r.Size < 1 || math.IsNaN(x.Score) {
+		return
+	}
+	if len(r.worst) < r.Size {
+		heap.Push(&r.worst, x)
+		return
+	}
+	if before(&x, &r.worst[0]) {
+		r.worst[0] = x
+		heap.Fix(&r.worst, 0)
+	}
+}
+
+// Sorted returns the ranked sequences from best to worst
+func (r *Ranking) Sorted() []Row {
+	sorted := make([]Row, len(r.worst))
+	copy(sorted, r.worst)
+	sort.Slice(sorted, func(i, j int) bool {
+		return before(&sorted[i], &sorted[j])
+	})
+	return sorted
+}
@@ -0,0 +1,43 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestRanking(t *testing.T) {
+	rows := []Row{
+		{Name: "A000005", Score: 0.5},
+		{Name: "A000001", Score: 0.25},
+		{Name: "A000004", Score: 0.5},
+		{Name: "A000002", Score: math.NaN()},
+		{Name: "A000003", Score: 1},
+		{Name: "A000006", Score: 0.125},
+	}
+	want := []string{"A000006", "A000001", "A000004", "A000005"}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 16; i++ {
+		ranking := NewRanking(len(want))
+		for _, j := range rng.Perm(len(rows)) {
+			ranking.Add(rows[j])
+		}
+		var got []string
+		for _, row := range ranking.Sorted() {
+			got = append(got, row.Name)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Sorted = %v, want %v", got, want)
+		}
+	}
+	empty := NewRanking(0)
+	empty.Add(rows[0])
+	if len(empty.Sorted()) != 0 {
+		t.Error("a ranking of size zero has rows")
+	}
+}
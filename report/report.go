@@ -0,0 +1,277 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package report writes rankings of OEIS sequences as Markdown, HTML, CSV or JSON.
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/pointlander/collatz/internal/atomicfile"
+)
+
+// ErrReadme is returned when a report would replace a README without force
+var ErrReadme = errors.New("report: refusing to overwrite a README")
+
+// Format is the file format of a report
+type Format int
+
+const (
+	// Markdown is a Markdown table
+	Markdown Format = iota
+	// HTML is an HTML page with a table
+	HTML
+	// CSV is a comma separated table with a header
+	CSV
+	// JSON is an array of objects
+	JSON
+)
+
+// Formats are the names of the formats
+var Formats = map[string]Format{
+	"markdown": Markdown,
+	"html":     HTML,
+	"csv":      CSV,
+	"json":     JSON,
+}
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	format, ok := Formats[name]
+	if !ok {
+		return Markdown, fmt.Errorf("report: unknown format %q", name)
+	}
+	return format, nil
+}
+
+// FormatOf returns the format of a path from its extension
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return Markdown, nil
+	case ".html", ".htm":
+		return HTML, nil
+	case ".csv":
+		return CSV, nil
+	case ".json":
+		return JSON, nil
+	}
+	return Markdown, fmt.Errorf("report: unknown format for %q", path)
+}
+
+// Row is a ranked sequence
+type Row struct {
+	Name         string
+	Description  string
+	Score        float64
+	Sum, Product string
+	Numbers      []string
+	// Length is the number of values that were scored
+	Length int
+}
+
+// Column is a column of a report, Key is one of the keys of Titles
+type Column struct {
+	Key, Title string
+}
+
+// Titles are the default titles of the columns by key
+var Titles = map[string]string{
+	"name":        "Name",
+	"description": "Description",
+	"score":       "Score",
+	"length":      "Length",
+	"sum":         "Sum",
+	"product":     "Product",
+	"numbers":     "Numbers",
+}
+
+// DefaultColumns are the keys of the columns of a report
+const DefaultColumns = "name,description,score,sum,product,numbers"
+
+// ParseColumns parses comma separated column keys
+func ParseColumns(s string) ([]Column, error) {
+	var columns []Column
+	for _, k := range strings.Split(strings.ReplaceAll(s, " ", ""), ",") {
+		title, ok := Titles[k]
+		if !ok {
+			return nil, fmt.Errorf("report: unknown column %q", k)
+		}
+		columns = append(columns, Column{Key: k, Title: title})
+	}
+	return columns, nil
+}
+
+// Cell returns the value of a column of a row
+func Cell(row Row, key string) string {
+	switch key {
+	case "name":
+		return row.Name
+	case "description":
+		return row.Description
+	case "score":
+		return fmt.Sprintf("%f", row.Score)
+	case "length":
+		return fmt.Sprint(row.Length)
+	case "sum":
+		return row.Sum
+	case "product":
+		return row.Product
+	case "numbers":
+		return fmt.Sprintf("%v", row.Numbers)
+	}
+	return ""
+}
+
+// Plot is an image embedded in a report
+type Plot struct {
+	Title, Path string
+}
+
+// Report is a ranking of sequences and the text around it
+type Report struct {
+	// Title is the title of an HTML page
+	Title    string
+	Headline string
+	Plots    []Plot
+	// Notes are paragraphs describing how the sequences were scored
+	Notes   []string
+	Columns []Column
+	Rows    []Row
+}
+
+var funcs = map[string]interface{}{
+	"cell": Cell,
+	"markdown": func(row Row, key string) string {
+		if key == "name" {
+			return fmt.Sprintf("[%s](https://oeis.org/%s)", row.Name, row.Name)
+		}
+		return strings.ReplaceAll(Cell(row, key), "|", "\\|")
+	},
+	"dashes": func(title string) string {
+		return strings.Repeat("-", len(title))
+	},
+}
+
+const markdown = `{{if .Headline}}{{.Headline}}
+{{end}}{{range .Plots}}![{{.Title}}]({{.Path}}?raw=true)
+{{end}}{{if or .Headline .Plots}}
+{{end}}{{range .Notes}}{{.}}
+
+{{end}}|{{range .Columns}} {{.Title}} |{{end}}
+|{{range .Columns}} {{dashes .Title}} |{{end}}
+{{range $row := .Rows}}|{{range $.Columns}} {{markdown $row .Key}} |{{end}}
+{{end}}`
+
+const html = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body>
+{{if .Headline}}<p>{{.Headline}}</p>
+{{end}}{{range .Plots}}<p><img src="{{.Path}}" alt="{{.Title}}"></p>
+{{end}}{{range .Notes}}<p>{{.}}</p>
+{{end}}<table>
+<tr>{{range .Columns}}<th>{{.Title}}</th>{{end}}</tr>
+{{range $row := .Rows}}<tr>{{range $.Columns}}<td>{{if eq .Key "name"}}<a href="https://oeis.org/{{$row.Name}}">{{$row.Name}}</a>{{else}}{{cell $row .Key}}{{end}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`
+
+// Write writes the report in format, a non empty custom template replaces the built in
+// template, HTML templates are escaped with html/template
+func (r *Report) Write(w io.Writer, format Format, custom string) error {
+	if custom == "" {
+		switch format {
+		case CSV:
+			return r.writeCSV(w)
+		case JSON:
+			return r.writeJSON(w)
+		case HTML:
+			custom = html
+		default:
+			custom = markdown
+		}
+	}
+	if format == HTML {
+		t, err := htmltemplate.New("report").Funcs(funcs).Parse(custom)
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, r)
+	}
+	t, err := template.New("report").Funcs(funcs).Parse(custom)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, r)
+}
+
+func (r *Report) writeCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	record := make([]string, len(r.Columns))
+	for i, column := range r.Columns {
+		record[i] = column.Title
+	}
+	if err := out.Write(record); err != nil {
+		return err
+	}
+	for _, row := range r.Rows {
+		for i, column := range r.Columns {
+			record[i] = Cell(row, column.Key)
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func (r *Report) writeJSON(w io.Writer) error {
+	rows := make([]map[string]interface{}, len(r.Rows))
+	for i, row := range r.Rows {
+		rows[i] = make(map[string]interface{}, len(r.Columns))
+		for _, column := range r.Columns {
+			switch column.Key {
+			case "score":
+				rows[i][column.Key] = row.Score
+			case "length":
+				rows[i][column.Key] = row.Length
+			case "numbers":
+				rows[i][column.Key] = row.Numbers
+			default:
+				rows[i][column.Key] = Cell(row, column.Key)
+			}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// Save writes the report to path, the file is replaced atomically and a README is only
+// replaced with force
+func (r *Report) Save(path string, format Format, custom string, force bool) error {
+	if !force && strings.HasPrefix(strings.ToUpper(filepath.Base(path)), "README") {
+		if _, err := os.Stat(path); err == nil {
+			return ErrReadme
+		}
+	}
+	return atomicfile.Write(path, func(out *os.File) error {
+		return r.Write(out, format, custom)
+	})
+}
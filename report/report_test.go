@@ -0,0 +1,173 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sample is a small report with values that must be escaped
+func sample(t *testing.T, columns string) *Report {
+	t.Helper()
+	parsed, err := ParseColumns(columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Report{
+		Title:    "Ranking <sequences>",
+		Headline: "The best sequences",
+		Plots:    []Plot{{Title: "scores", Path: "scores.png"}},
+		Notes:    []string{"Scored by sum and product"},
+		Columns:  parsed,
+		Rows: []Row{
+			{Name: "A000045", Description: "Fibonacci | Lucas", Score: 0.5, Sum: "1/2", Product: "1/4",
+				Numbers: []string{"1", "2", "3"}, Length: 3},
+			{Name: "A000027", Description: "<b>integers</b> & more", Score: 0.25, Sum: "1", Product: "2",
+				Numbers: []string{"1", "2"}, Length: 2},
+		},
+	}
+}
+
+func write(t *testing.T, r *Report, format Format, custom string) string {
+	t.Helper()
+	buffer := bytes.Buffer{}
+	if err := r.Write(&buffer, format, custom); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.String()
+}
+
+func TestMarkdown(t *testing.T) {
+	got := write(t, sample(t, "name,description,score"), Markdown, "")
+	want := `The best sequences
+![scores](scores.png?raw=true)
+
+Scored by sum and product
+
+| Name | Description | Score |
+| ---- | ----------- | ----- |
+| [A000045](https://oeis.org/A000045) | Fibonacci \| Lucas | 0.500000 |
+| [A000027](https://oeis.org/A000027) | <b>integers</b> & more | 0.250000 |
+`
+	if got != want {
+		t.Errorf("Markdown =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	got := write(t, sample(t, "name,description,numbers"), HTML, "")
+	for _, want := range []string{
+		"<title>Ranking &lt;sequences&gt;</title>",
+		`<img src="scores.png" alt="scores">`,
+		`<td><a href="https://oeis.org/A000045">A000045</a></td>`,
+		"<td>&lt;b&gt;integers&lt;/b&gt; &amp; more</td>",
+		"<td>[1 2 3]</td>",
+		"<th>Numbers</th>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<b>") || strings.Contains(got, "<th>Score</th>") {
+		t.Errorf("HTML has unescaped or unselected text:\n%s", got)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	got := write(t, sample(t, "length,name,description"), CSV, "")
+	records, err := csv.NewReader(strings.NewReader(got)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		{"Length", "Name", "Description"},
+		{"3", "A000045", "Fibonacci | Lucas"},
+		{"2", "A000027", "<b>integers</b> & more"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV = %v, want %v", records, want)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	got := write(t, sample(t, "name,score,length,numbers"), JSON, "")
+	var rows []map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &rows); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{"name": "A000045", "score": 0.5, "length": 3.0, "numbers": []interface{}{"1", "2", "3"}},
+		{"name": "A000027", "score": 0.25, "length": 2.0, "numbers": []interface{}{"1", "2"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("JSON = %v, want %v", rows, want)
+	}
+}
+
+func TestCustom(t *testing.T) {
+	custom := `{{range .Rows}}{{.Name}}: {{cell . "description"}}
+{{end}}`
+	r := sample(t, DefaultColumns)
+	if got, want := write(t, r, Markdown, custom), "A000045: Fibonacci | Lucas\nA000027: <b>integers</b> & more\n"; got != want {
+		t.Errorf("custom Markdown = %q, want %q", got, want)
+	}
+	if got, want := write(t, r, HTML, custom), "A000045: Fibonacci | Lucas\nA000027: &lt;b&gt;integers&lt;/b&gt; &amp; more\n"; got != want {
+		t.Errorf("custom HTML = %q, want %q", got, want)
+	}
+	if got, want := write(t, r, CSV, `{{len .Rows}}`), "2"; got != want {
+		t.Errorf("custom CSV = %q, want %q", got, want)
+	}
+	if err := r.Write(&bytes.Buffer{}, Markdown, "{{.Missing"); err == nil {
+		t.Error("wrote an invalid template")
+	}
+	if _, err := ParseColumns("name,rank"); err == nil {
+		t.Error("parsed an unknown column")
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	r := sample(t, DefaultColumns)
+	readme := filepath.Join(dir, "README.md")
+	if err := r.Save(readme, Markdown, "", false); err != nil {
+		t.Fatalf("Save of a new README = %v", err)
+	}
+	if err := os.WriteFile(readme, []byte("hand written"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Save(readme, Markdown, "", false); !errors.Is(err, ErrReadme) {
+		t.Errorf("Save over a README = %v, want %v", err, ErrReadme)
+	}
+	if data, _ := os.ReadFile(readme); string(data) != "hand written" {
+		t.Errorf("README was replaced with %q", data)
+	}
+	if err := r.Save(readme, Markdown, "", true); err != nil {
+		t.Fatalf("forced Save over a README = %v", err)
+	}
+	if data, _ := os.ReadFile(readme); string(data) != write(t, r, Markdown, "") {
+		t.Errorf("forced Save wrote %q", data)
+	}
+
+	path := filepath.Join(dir, "ranking.md")
+	for i := 0; i < 2; i++ {
+		if err := r.Save(path, Markdown, "", false); err != nil {
+			t.Fatalf("Save = %v", err)
+		}
+	}
+	if err := r.Save(path, Markdown, "{{.Missing", false); err == nil {
+		t.Error("saved with an invalid template")
+	}
+	if data, _ := os.ReadFile(path); string(data) != write(t, r, Markdown, "") {
+		t.Errorf("a failed Save replaced %s with %q", path, data)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package series generates series of integers to be tested.
+package series
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"math/rand"
+	"sort"
+)
+
+// Arithmetic returns the series a + b*i for i in [0, size)
+func Arithmetic(a, b *big.Int, size int) []big.Int {
+	series := make([]big.Int, size)
+	for i := range series {
+		x := &series[i]
+		x.SetInt64(int64(i)).Mul(b, x).Add(a, x)
+	}
+	return series
+}
+
+// Geometric returns the series a * b^i for i in [0, size)
+func Geometric(a, b *big.Int, size int) []big.Int {
+	series := make([]big.Int, size)
+	for i := range series {
+		x := &series[i]
+		x.SetInt64(int64(i)).Exp(b, x, nil).Mul(a, x)
+	}
+	return series
+}
+
+// Element is an element of the periodic table
+type Element struct {
+	Name         string  `json:"name"`
+	Appearance   string  `json:"appearance"`
+	AtomicMass   float64 `json:"atomic_mass"`
+	Boil         float64 `json:"boil"`
+	Category     string  `json:"category"`
+	Color        string  `json:"color"`
+	Density      float64 `json:"density"`
+	DiscoveredBy string  `json:"discovered_by"`
+	Melt         float64 `json:"melt"`
+	MolarHeat    float64 `json:"molar_heat"`
+	NamedBy      string  `json:"named_by"`
+	Number       int     `json:"number"`
+	Period       int     `json:"period"`
+	Phase        string  `json:"phase"`
+	Source       string  `json:"source"`
+	SpectralImg  string  `json:"spectral_img"`
+	Summary      string  `json:"summary"`
+	Symbol       string  `json:"symbol"`
+	XPos         int     `json:"xpos"`
+	YPos         int     `json:"ypos"`
+	Shells       []int   `json:"shells"`
+}
+
+// Elements is the periodic table
+type Elements struct {
+	Elements []Element `json:"elements"`
+}
+
+// Atomic returns the sorted unique neutron counts of the periodic table stored in the json file at path
+func Atomic(path string) ([]big.Int, error) {
+	series, elements, neutrons := make([]big.Int, 0, 256), Elements{}, make(map[int]bool)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &elements)
+	if err != nil {
+		return nil, err
+	}
+	for _, element := range elements.Elements {
+		n := int(math.Round(element.AtomicMass)) - element.Number
+		neutrons[n] = true
+	}
+	sorted := make([]int, 0, len(neutrons))
+	for n := range neutrons {
+		sorted = append(sorted, n)
+	}
+	sort.Ints(sorted)
+	for _, n := range sorted {
+		series = append(series, *big.NewInt(int64(n)))
+	}
+	return series, nil
+}
+
+// Random returns size unique random numbers generated from seed
+func Random(size int, seed int64) []big.Int {
+	series, rnd, dupe := make([]big.Int, size), rand.New(rand.NewSource(seed)), make(map[uint64]bool)
+	for i := range series {
+		number := rnd.Uint64()
+		for dupe[number] {
+			number = rnd.Uint64()
+		}
+		dupe[number] = true
+		series[i].SetUint64(number)
+	}
+	return series
+}
+
+var primes = [...]int{2, 3, 5, 7}
+
+func isSevenSmooth(number int) bool {
+	for _, p := range primes {
+		for number%p == 0 {
+			number /= p
+		}
+	}
+	return number == 1
+}
+
+func filter(size int, keep func(number int) bool) []big.Int {
+	series := make([]big.Int, 0, size)
+	i := 1
+	for len(series) < size {
+		if keep(i) {
+			smooth := big.Int{}
+			smooth.SetInt64(int64(i))
+			series = append(series, smooth)
+		}
+		i++
+	}
+	return series
+}
+
+// SevenSmooth returns the first size seven smooth numbers, A002473
+func SevenSmooth(size int) []big.Int {
+	return filter(size, isSevenSmooth)
+}
+
+// SevenSmoothComplement returns the first size numbers that are not seven smooth
+func SevenSmoothComplement(size int) []big.Int {
+	return filter(size, func(number int) bool {
+		return !isSevenSmooth(number)
+	})
+}
+
+// Source is a named generator of series, Generate returns the first size terms so smaller
+// series are prefixes of larger ones
+type Source struct {
+	Generate  func(size int) []big.Int
+	Key, Nice string
+}
+
+// Registry is the set of known sources
+var Registry = map[string]Source{
+	"sevenSmooth": {
+		Generate: SevenSmooth,
+		Key:      "sevenSmooth",
+		Nice:     "seven smooth",
+	},
+	"sevenSmoothComplement": {
+		Generate: SevenSmoothComplement,
+		Key:      "sevenSmoothComplement",
+		Nice:     "seven smooth complement",
+	},
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sumproduct
+
+import (
+	"math"
+	"math/big"
+)
+
+// Energy is the additive and multiplicative energy of a series, the number of solutions to
+// a+b = c+d and a*b = c*d, along with the representation counts they are computed from
+type Energy struct {
+	Sum, Product int64
+	// SumHistogram[r] is the number of sums with exactly r ordered representations a+b,
+	// and ProductHistogram[r] the same for products
+	SumHistogram, ProductHistogram map[int]int
+}
+
+// energy returns the energy of a set
+func energy(s set) Energy {
+	e := Energy{}
+	e.Sum, e.Product = s.energies()
+	e.SumHistogram, e.ProductHistogram = s.histograms()
+	return e
+}
+
+// Normalized returns the energies divided by |A|^3, the largest possible energy of a set
+func (e Energy) Normalized(length int) (float64, float64) {
+	if length == 0 {
+		return 0, 0
+	}
+	cube := math.Pow(float64(length), 3)
+	return float64(e.Sum) / cube, float64(e.Product) / cube
+}
+
+// NewEnergy computes the additive and multiplicative energy of series
+func NewEnergy(series []big.Int) Energy {
+	return energy(fill(series))
+}
+
+// Energy returns the additive and multiplicative energy of the series
+func (s *Scorer) Energy() Energy {
+	return energy(s.set)
+}
+
+// Energies returns the additive and multiplicative energy of the series without the histograms
+func (s *Scorer) Energies() (int64, int64) {
+	return s.set.energies()
+}
@@ -0,0 +1,345 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sumproduct
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Expander is a set built from a series A such as A+A or A*A
+type Expander int
+
+const (
+	// Sum is the sumset A+A
+	Sum Expander = iota
+	// Product is the product set A*A
+	Product
+	// Difference is the difference set A-A
+	Difference
+	// Quotient is the ratio set A/A of reduced fractions
+	Quotient
+	// Shifted is the set A(A+1)
+	Shifted
+	// SumProduct is the set A+A*A, the composition of Sum and Product
+	SumProduct
+)
+
+// composed is the first composed expander, Compose encodes the outer and inner operations above it
+const composed Expander = 16
+
+// Compose returns the expander A∘(A⋄A) where ∘ is the operation of outer and ⋄ is the operation
+// of inner, both must be Sum, Product, Difference or Quotient
+func Compose(outer, inner Expander) (Expander, error) {
+	if !outer.operation() || !inner.operation() {
+		return Sum, fmt.Errorf("sumproduct: can not compose %v and %v", outer, inner)
+	}
+	if outer == Sum && inner == Product {
+		return SumProduct, nil
+	}
+	return composed + 4*outer + inner, nil
+}
+
+// operation returns true if the expander is a single binary operation that can be composed
+func (e Expander) operation() bool {
+	return e >= Sum && e <= Quotient
+}
+
+// Operations returns the outer and inner operations of a composed expander, ok is false for
+// expanders that are not compositions
+func (e Expander) Operations() (outer, inner Expander, ok bool) {
+	if e == SumProduct {
+		return Sum, Product, true
+	}
+	if e < composed || e >= composed+16 {
+		return Sum, Sum, false
+	}
+	return (e - composed) / 4, (e - composed) % 4, true
+}
+
+// Divides returns true if the expander divides elements so it needs a Divider
+func (e Expander) Divides() bool {
+	if outer, inner, ok := e.Operations(); ok {
+		return outer == Quotient || inner == Quotient
+	}
+	return e == Quotient
+}
+
+// Expanders are the names of the expanders
+var Expanders = map[string]Expander{
+	"sum":        Sum,
+	"product":    Product,
+	"difference": Difference,
+	"quotient":   Quotient,
+	"shifted":    Shifted,
+	"sumproduct": SumProduct,
+}
+
+// ParseExpander returns the expander with the given name, or the composition of two operations
+// written outer-inner such as "difference-product" for A-A*A
+func ParseExpander(name string) (Expander, error) {
+	if outer, inner, ok := strings.Cut(name, "-"); ok {
+		o, err := ParseExpander(outer)
+		if err != nil {
+			return Sum, err
+		}
+		i, err := ParseExpander(inner)
+		if err != nil {
+			return Sum, err
+		}
+		return Compose(o, i)
+	}
+	expander, ok := Expanders[name]
+	if !ok {
+		return Sum, fmt.Errorf("sumproduct: unknown expander %q", name)
+	}
+	return expander, nil
+}
+
+// String returns the name of the expander in the form accepted by ParseExpander
+func (e Expander) String() string {
+	for name, expander := range Expanders {
+		if expander == e {
+			return name
+		}
+	}
+	if outer, inner, ok := e.Operations(); ok {
+		return outer.String() + "-" + inner.String()
+	}
+	return "unknown"
+}
+
+// Max returns the number of distinct expressions that make up the expander of a series of
+// length values, the largest possible size of the expander used to normalize the scores
+func (e Expander) Max(length int) int {
+	switch e {
+	case Difference, Quotient:
+		if length == 0 {
+			return 0
+		}
+		return length*(length-1) + 1
+	case Shifted:
+		return length * length
+	}
+	if _, inner, ok := e.Operations(); ok {
+		return length * inner.Max(length)
+	}
+	return Max(length)
+}
+
+// Pair is the two expanders that are scored against each other, A+A and A*A by default
+type Pair [2]Expander
+
+// Default is the pair of the sumset and the product set
+var Default = Pair{Sum, Product}
+
+// ParsePair parses two comma separated expander names such as "difference,quotient" or
+// "sum-product,product-sum"
+func ParsePair(s string) (Pair, error) {
+	names := strings.Split(strings.ReplaceAll(s, " ", ""), ",")
+	if len(names) != 2 {
+		return Default, fmt.Errorf("sumproduct: expected two expanders in %q", s)
+	}
+	pair := Pair{}
+	for i, name := range names {
+		expander, err := ParseExpander(name)
+		if err != nil {
+			return Default, err
+		}
+		pair[i] = expander
+	}
+	return pair, nil
+}
+
+// String formats the pair in the form accepted by ParsePair
+func (p Pair) String() string {
+	return p[0].String() + "," + p[1].String()
+}
+
+// Divides returns true if either expander divides elements
+func (p Pair) Divides() bool {
+	return p[0].Divides() || p[1].Divides()
+}
+
+// expansion is the set of distinct values of an expander
+type expansion struct {
+	expander Expander
+	values   []Element
+	seen     map[string]struct{}
+}
+
+func newExpansion(expander Expander, size int) *expansion {
+	return &expansion{
+		expander: expander,
+		seen:     make(map[string]struct{}, size),
+	}
+}
+
+// add appends v and adds every new value of the expander that involves v
+func (e *expansion) add(v Element) error {
+	if outer, inner, ok := e.expander.Operations(); ok {
+		return e.compose(outer, inner, v)
+	}
+	e.values = append(e.values, v)
+	switch e.expander {
+	case Sum:
+		for _, y := range e.values {
+			e.insert(v.Add(y))
+		}
+	case Product:
+		for _, y := range e.values {
+			e.insert(v.Mul(y))
+		}
+	case Difference:
+		for _, y := range e.values {
+			e.insert(v.Sub(y))
+			e.insert(y.Sub(v))
+		}
+	case Quotient:
+		d, ok := v.(Divider)
+		if !ok {
+			return fmt.Errorf("sumproduct: %T can not be divided", v)
+		}
+		for _, y := range e.values {
+			if q, ok := d.Quo(y); ok {
+				e.insert(q)
+			}
+			if q, ok := y.(Divider).Quo(v); ok {
+				e.insert(q)
+			}
+		}
+	case Shifted:
+		one := v.One()
+		for _, y := range e.values {
+			e.insert(v.Mul(y.Add(one)))
+			e.insert(y.Mul(v.Add(one)))
+		}
+	}
+	return nil
+}
+
+// compose appends v and adds every new value x∘(y⋄z) that involves v, integers are divided as
+// rationals so that the inner quotients can be combined with them
+func (e *expansion) compose(outer, inner Expander, v Element) error {
+	if outer == Quotient || inner == Quotient {
+		if i, ok := v.(*Integer); ok {
+			r := &Rational{}
+			r.SetInt(&i.Int)
+			v = r
+		}
+		if _, ok := v.(Divider); !ok {
+			return fmt.Errorf("sumproduct: %T can not be divided", v)
+		}
+	}
+	e.values = append(e.values, v)
+	for _, y := range e.values {
+		for _, z := range e.values {
+			e.apply(outer, inner, v, y, z)
+			e.apply(outer, inner, y, v, z)
+			e.apply(outer, inner, y, z, v)
+		}
+	}
+	return nil
+}
+
+// apply inserts x∘(y⋄z) unless it divides by zero
+func (e *expansion) apply(outer, inner Expander, x, y, z Element) {
+	if w, ok := operate(inner, y, z); ok {
+		if w, ok = operate(outer, x, w); ok {
+			e.insert(w)
+		}
+	}
+}
+
+// operate returns x op y for the operation of a Sum, Product, Difference or Quotient expander
+func operate(op Expander, x, y Element) (Element, bool) {
+	switch op {
+	case Sum:
+		return x.Add(y), true
+	case Product:
+		return x.Mul(y), true
+	case Difference:
+		return x.Sub(y), true
+	}
+	return x.(Divider).Quo(y)
+}
+
+var one = big.NewInt(1)
+
+func (e *expansion) insert(x Element) {
+	e.seen[x.Key()] = struct{}{}
+}
+
+func (e *expansion) size() int {
+	return len(e.seen)
+}
+
+// NewPairMeasure measures series with a pair of expanders
+func NewPairMeasure(series []big.Int, pair Pair) (Measure, error) {
+	if pair == Default {
+		return NewMeasure(series), nil
+	}
+	return NewRingMeasure(Integers(series), pair)
+}
+
+// NewRingMeasure measures elements of any ring with a pair of expanders, the quotient expander
+// is only supported by elements that implement Divider
+func NewRingMeasure(elements []Element, pair Pair) (Measure, error) {
+	size := Max(len(elements))
+	first, second := newExpansion(pair[0], size), newExpansion(pair[1], size)
+	for _, x := range elements {
+		if err := first.add(x); err != nil {
+			return Measure{}, err
+		}
+		if err := second.add(x); err != nil {
+			return Measure{}, err
+		}
+	}
+	return Measure{
+		Length:   len(elements),
+		Sums:     first.size(),
+		Products: second.size(),
+		Pair:     pair,
+	}, nil
+}
+
+// RingScorer incrementally maintains the expanders of a growing series of ring elements
+type RingScorer struct {
+	length        int
+	pair          Pair
+	first, second *expansion
+}
+
+// NewRingScorer creates an empty RingScorer for the expanders of pair
+func NewRingScorer(pair Pair) *RingScorer {
+	return &RingScorer{
+		pair:   pair,
+		first:  newExpansion(pair[0], 256),
+		second: newExpansion(pair[1], 256),
+	}
+}
+
+// Add appends x to the series
+func (s *RingScorer) Add(x Element) error {
+	if err := s.first.add(x); err != nil {
+		return err
+	}
+	if err := s.second.add(x); err != nil {
+		return err
+	}
+	s.length++
+	return nil
+}
+
+// Measure returns the measure of the series
+func (s *RingScorer) Measure() Measure {
+	return Measure{
+		Length:   s.length,
+		Sums:     s.first.size(),
+		Products: s.second.size(),
+		Pair:     s.pair,
+	}
+}
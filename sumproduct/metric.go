@@ -0,0 +1,149 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sumproduct
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Measure is the size of a series and of its sumset and product set, or of the two expanders of Pair
+type Measure struct {
+	Length, Sums, Products int
+	Pair                   Pair
+}
+
+// NewMeasure measures series
+func NewMeasure(series []big.Int) Measure {
+	sums, products := Sizes(series)
+	return Measure{
+		Length:   len(series),
+		Sums:     sums,
+		Products: products,
+		Pair:     Default,
+	}
+}
+
+// Measure returns the measure of the series
+func (s *Scorer) Measure() Measure {
+	sums, products := s.set.sizes()
+	if s.pair != Default {
+		sums, products = s.first.size(), s.second.size()
+	}
+	return Measure{
+		Length:   len(s.values),
+		Sums:     sums,
+		Products: products,
+		Pair:     s.pair,
+	}
+}
+
+// Ratios returns the sizes of the sumset and the product set normalized by the Max of their expanders
+func (m Measure) Ratios() (float64, float64) {
+	if m.Length == 0 {
+		return 0, 0
+	}
+	return float64(m.Sums) / float64(m.Pair[0].Max(m.Length)), float64(m.Products) / float64(m.Pair[1].Max(m.Length))
+}
+
+// Exact returns the sizes of the sumset and the product set normalized by the Max of their expanders
+// as exact rationals
+func (m Measure) Exact() (*big.Rat, *big.Rat) {
+	if m.Length == 0 {
+		return new(big.Rat), new(big.Rat)
+	}
+	return big.NewRat(int64(m.Sums), int64(m.Pair[0].Max(m.Length))),
+		big.NewRat(int64(m.Products), int64(m.Pair[1].Max(m.Length)))
+}
+
+// Exponents returns e such that |A+A| = |A|^e and |A*A| = |A|^e
+func (m Measure) Exponents() (float64, float64) {
+	if m.Length < 2 {
+		return 0, 0
+	}
+	n := math.Log(float64(m.Length))
+	return math.Log(float64(m.Sums)) / n, math.Log(float64(m.Products)) / n
+}
+
+// Metric is a way of turning a Measure into a score where lower scores mean less expansion
+type Metric int
+
+const (
+	// Ratio is the length of the vector of ratios of sumset and product set sizes to n(n+1)/2
+	Ratio Metric = iota
+	// Count is the classic |A+A| + |A*A|
+	Count
+	// Exponent is max(|A+A|, |A*A|) / |A|^(1+epsilon)
+	Exponent
+	// Exact is Ratio computed from exact rational ratios
+	Exact
+)
+
+// Metrics are the names of the metrics
+var Metrics = map[string]Metric{
+	"ratio":    Ratio,
+	"count":    Count,
+	"exponent": Exponent,
+	"exact":    Exact,
+}
+
+// ParseMetric returns the metric with the given name
+func ParseMetric(name string) (Metric, error) {
+	metric, ok := Metrics[name]
+	if !ok {
+		return Ratio, fmt.Errorf("sumproduct: unknown metric %q", name)
+	}
+	return metric, nil
+}
+
+// String returns the name of the metric
+func (m Metric) String() string {
+	for name, metric := range Metrics {
+		if metric == m {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Score scores a measure, epsilon is only used by Exponent
+func (m Metric) Score(measure Measure, epsilon float64) float64 {
+	switch m {
+	case Count:
+		return float64(measure.Sums + measure.Products)
+	case Exponent:
+		max := measure.Sums
+		if measure.Products > max {
+			max = measure.Products
+		}
+		return float64(max) / math.Pow(float64(measure.Length), 1+epsilon)
+	case Exact:
+		sum, product := measure.Exact()
+		x, y := big.Rat{}, big.Rat{}
+		x.Mul(sum, sum).Add(&x, y.Mul(product, product))
+		f, _ := x.Float64()
+		return math.Sqrt(f)
+	}
+	sum, product := measure.Ratios()
+	return Score(sum, product)
+}
+
+// Format formats the sum and product components of a measure for the metric, floating point
+// components are formatted with verb
+func (m Metric) Format(measure Measure, verb string) (string, string) {
+	switch m {
+	case Count:
+		return fmt.Sprint(measure.Sums), fmt.Sprint(measure.Products)
+	case Exponent:
+		sum, product := measure.Exponents()
+		return fmt.Sprintf(verb, sum), fmt.Sprintf(verb, product)
+	case Exact:
+		sum, product := measure.Exact()
+		return sum.String(), product.String()
+	}
+	sum, product := measure.Ratios()
+	return fmt.Sprintf(verb, sum), fmt.Sprintf(verb, product)
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sumproduct
+
+import (
+	"errors"
+	"math/big"
+)
+
+// ErrModulus is returned for a modulus less than two
+var ErrModulus = errors.New("sumproduct: modulus must be at least two")
+
+// Reduce returns the distinct residues of series mod modulus in the order they first appear
+func Reduce(series []big.Int, modulus *big.Int) ([]big.Int, error) {
+	if modulus.Cmp(big.NewInt(2)) < 0 {
+		return nil, ErrModulus
+	}
+	residues, seen := make([]big.Int, 0, len(series)), make(map[string]bool, len(series))
+	residue := big.Int{}
+	for i := range series {
+		residue.Mod(&series[i], modulus)
+		k := key(&residue)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		residues = append(residues, big.Int{})
+		residues[len(residues)-1].Set(&residue)
+	}
+	return residues, nil
+}
+
+// NewModularMeasure measures the sumset and product set of series reduced mod modulus, the
+// length of the measure is the number of distinct residues
+func NewModularMeasure(series []big.Int, modulus *big.Int) (Measure, error) {
+	residues, err := Reduce(series, modulus)
+	if err != nil {
+		return Measure{}, err
+	}
+	measure := Measure{Length: len(residues), Pair: Default}
+	if modulus.BitLen() <= 32 {
+		measure.Sums, measure.Products = modularSizes64(residues, modulus.Uint64())
+	} else {
+		measure.Sums, measure.Products = modularSizesBig(residues, modulus)
+	}
+	return measure, nil
+}
+
+// modularSizes64 returns the sizes of the sumset and product set of residues less than 2^32
+func modularSizes64(residues []big.Int, modulus uint64) (int, int) {
+	values := make([]uint64, len(residues))
+	for i := range residues {
+		values[i] = residues[i].Uint64()
+	}
+	size := Max(len(values))
+	sums, products := make(map[uint64]bool, size), make(map[uint64]bool, size)
+	for i, x := range values {
+		for _, y := range values[:i+1] {
+			sums[(x+y)%modulus] = true
+			products[(x*y)%modulus] = true
+		}
+	}
+	return len(sums), len(products)
+}
+
+// modularSizesBig returns the sizes of the sumset and product set of residues of any size
+func modularSizesBig(residues []big.Int, modulus *big.Int) (int, int) {
+	size := Max(len(residues))
+	sums, products := make(map[string]bool, size), make(map[string]bool, size)
+	x := big.Int{}
+	for i := range residues {
+		for j := range residues[:i+1] {
+			sums[key(x.Add(&residues[i], &residues[j]).Mod(&x, modulus))] = true
+			products[key(x.Mul(&residues[i], &residues[j]).Mod(&x, modulus))] = true
+		}
+	}
+	return len(sums), len(products)
+}
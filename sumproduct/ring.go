@@ -0,0 +1,350 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sumproduct
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+)
+
+// Element is an element of a commutative ring
+type Element interface {
+	Add(y Element) Element
+	Sub(y Element) Element
+	Mul(y Element) Element
+	// One returns the multiplicative identity of the ring
+	One() Element
+	// Key returns a canonical hash key, equal elements have equal keys
+	Key() string
+}
+
+// Divider is an Element that can also be divided, ok is false when y is zero
+type Divider interface {
+	Quo(y Element) (quotient Element, ok bool)
+}
+
+// lengthPrefixed joins keys so that the boundaries between them are unambiguous
+func lengthPrefixed(keys ...string) string {
+	var joined []byte
+	prefix := [binary.MaxVarintLen64]byte{}
+	for _, k := range keys {
+		n := binary.PutUvarint(prefix[:], uint64(len(k)))
+		joined = append(append(joined, prefix[:n]...), k...)
+	}
+	return string(joined)
+}
+
+// Integer is an element of the integers
+type Integer struct {
+	big.Int
+}
+
+// NewInteger returns x as an Integer
+func NewInteger(x *big.Int) *Integer {
+	i := &Integer{}
+	i.Set(x)
+	return i
+}
+
+// Add returns x+y
+func (x *Integer) Add(y Element) Element {
+	z := &Integer{}
+	z.Int.Add(&x.Int, &y.(*Integer).Int)
+	return z
+}
+
+// Sub returns x-y
+func (x *Integer) Sub(y Element) Element {
+	z := &Integer{}
+	z.Int.Sub(&x.Int, &y.(*Integer).Int)
+	return z
+}
+
+// Mul returns x*y
+func (x *Integer) Mul(y Element) Element {
+	z := &Integer{}
+	z.Int.Mul(&x.Int, &y.(*Integer).Int)
+	return z
+}
+
+// One returns 1
+func (x *Integer) One() Element {
+	return NewInteger(one)
+}
+
+// Key returns the sign and magnitude of x
+func (x *Integer) Key() string {
+	return key(&x.Int)
+}
+
+// Quo returns x/y as a reduced Rational
+func (x *Integer) Quo(y Element) (Element, bool) {
+	d := &y.(*Integer).Int
+	if d.Sign() == 0 {
+		return nil, false
+	}
+	z := &Rational{}
+	z.SetFrac(&x.Int, d)
+	return z, true
+}
+
+// Rational is an element of the rationals
+type Rational struct {
+	big.Rat
+}
+
+// Add returns x+y
+func (x *Rational) Add(y Element) Element {
+	z := &Rational{}
+	z.Rat.Add(&x.Rat, &y.(*Rational).Rat)
+	return z
+}
+
+// Sub returns x-y
+func (x *Rational) Sub(y Element) Element {
+	z := &Rational{}
+	z.Rat.Sub(&x.Rat, &y.(*Rational).Rat)
+	return z
+}
+
+// Mul returns x*y
+func (x *Rational) Mul(y Element) Element {
+	z := &Rational{}
+	z.Rat.Mul(&x.Rat, &y.(*Rational).Rat)
+	return z
+}
+
+// One returns 1
+func (x *Rational) One() Element {
+	z := &Rational{}
+	z.SetInt64(1)
+	return z
+}
+
+// Key returns the reduced numerator and denominator of x
+func (x *Rational) Key() string {
+	return lengthPrefixed(key(x.Num()), string(x.Denom().Bytes()))
+}
+
+// Quo returns x/y
+func (x *Rational) Quo(y Element) (Element, bool) {
+	d := &y.(*Rational).Rat
+	if d.Sign() == 0 {
+		return nil, false
+	}
+	z := &Rational{}
+	z.Rat.Quo(&x.Rat, d)
+	return z, true
+}
+
+// Gaussian is a Gaussian integer Re + Im*i
+type Gaussian struct {
+	Re, Im big.Int
+}
+
+// Add returns x+y
+func (x *Gaussian) Add(y Element) Element {
+	g, z := y.(*Gaussian), &Gaussian{}
+	z.Re.Add(&x.Re, &g.Re)
+	z.Im.Add(&x.Im, &g.Im)
+	return z
+}
+
+// Sub returns x-y
+func (x *Gaussian) Sub(y Element) Element {
+	g, z := y.(*Gaussian), &Gaussian{}
+	z.Re.Sub(&x.Re, &g.Re)
+	z.Im.Sub(&x.Im, &g.Im)
+	return z
+}
+
+// Mul returns x*y
+func (x *Gaussian) Mul(y Element) Element {
+	g, z, t := y.(*Gaussian), &Gaussian{}, big.Int{}
+	z.Re.Mul(&x.Re, &g.Re)
+	z.Re.Sub(&z.Re, t.Mul(&x.Im, &g.Im))
+	z.Im.Mul(&x.Re, &g.Im)
+	z.Im.Add(&z.Im, t.Mul(&x.Im, &g.Re))
+	return z
+}
+
+// One returns 1
+func (x *Gaussian) One() Element {
+	z := &Gaussian{}
+	z.Re.SetInt64(1)
+	return z
+}
+
+// Key returns the real and imaginary parts of x
+func (x *Gaussian) Key() string {
+	return lengthPrefixed(key(&x.Re), key(&x.Im))
+}
+
+// Poly is a polynomial over GF(2) where bit i of the non-negative integer is the coefficient of x^i
+type Poly struct {
+	big.Int
+}
+
+// Add returns x+y, which is the exclusive or of the coefficients
+func (x *Poly) Add(y Element) Element {
+	z := &Poly{}
+	z.Xor(&x.Int, &y.(*Poly).Int)
+	return z
+}
+
+// Sub returns x-y, which is the same as x+y
+func (x *Poly) Sub(y Element) Element {
+	return x.Add(y)
+}
+
+// Mul returns the carry-less product x*y
+func (x *Poly) Mul(y Element) Element {
+	a, b := &x.Int, &y.(*Poly).Int
+	if a.BitLen() < b.BitLen() {
+		a, b = b, a
+	}
+	z, shifted := &Poly{}, big.Int{}
+	for i := 0; i < b.BitLen(); i++ {
+		if b.Bit(i) == 1 {
+			z.Xor(&z.Int, shifted.Lsh(a, uint(i)))
+		}
+	}
+	return z
+}
+
+// One returns 1
+func (x *Poly) One() Element {
+	z := &Poly{}
+	z.SetInt64(1)
+	return z
+}
+
+// Key returns the coefficients of x
+func (x *Poly) Key() string {
+	return string(x.Bytes())
+}
+
+// Ring is a named conversion of integer series into elements of a ring
+type Ring struct {
+	Convert   func(series []big.Int) []Element
+	Key, Nice string
+}
+
+// Rings are the known rings
+var Rings = map[string]Ring{
+	"integer": {
+		Convert: Integers,
+		Key:     "integer",
+		Nice:    "integer",
+	},
+	"rational": {
+		Convert: Reciprocals,
+		Key:     "rational",
+		Nice:    "reciprocal",
+	},
+	"gaussian": {
+		Convert: Gaussians,
+		Key:     "gaussian",
+		Nice:    "Gaussian integer",
+	},
+	"gf2": {
+		Convert: Polys,
+		Key:     "gf2",
+		Nice:    "GF(2) polynomial",
+	},
+}
+
+// Divides returns true if the elements of the ring implement Divider
+func (r Ring) Divides() bool {
+	sample := make([]big.Int, 2)
+	sample[0].SetInt64(1)
+	sample[1].SetInt64(1)
+	elements := r.Convert(sample)
+	if len(elements) == 0 {
+		return false
+	}
+	_, ok := elements[0].(Divider)
+	return ok
+}
+
+// Check returns an error if the ring does not support the expanders of pair
+func (r Ring) Check(pair Pair) error {
+	if pair.Divides() && !r.Divides() {
+		return fmt.Errorf("sumproduct: the %s ring does not support the expanders %v", r.Key, pair)
+	}
+	return nil
+}
+
+// ParseRing returns the ring with the given name
+func ParseRing(name string) (Ring, error) {
+	ring, ok := Rings[name]
+	if !ok {
+		return Rings["integer"], fmt.Errorf("sumproduct: unknown ring %q", name)
+	}
+	return ring, nil
+}
+
+// Integers converts series into Integers
+func Integers(series []big.Int) []Element {
+	elements := make([]Element, len(series))
+	for i := range series {
+		elements[i] = NewInteger(&series[i])
+	}
+	return elements
+}
+
+// Reciprocals converts series into the Rationals 1/x, zeros are dropped
+func Reciprocals(series []big.Int) []Element {
+	elements := make([]Element, 0, len(series))
+	for i := range series {
+		if series[i].Sign() == 0 {
+			continue
+		}
+		x := &Rational{}
+		x.SetFrac(one, &series[i])
+		elements = append(elements, x)
+	}
+	return elements
+}
+
+// Gaussians converts consecutive pairs of terms a, b of series into the Gaussian integers a + b*i,
+// a trailing unpaired term is dropped
+func Gaussians(series []big.Int) []Element {
+	elements := make([]Element, 0, len(series)/2)
+	for i := 0; i+1 < len(series); i += 2 {
+		x := &Gaussian{}
+		x.Re.Set(&series[i])
+		x.Im.Set(&series[i+1])
+		elements = append(elements, x)
+	}
+	return elements
+}
+
+// Polys converts series into polynomials over GF(2) whose coefficients are the binary digits of
+// the absolute value of each term
+func Polys(series []big.Int) []Element {
+	elements := make([]Element, len(series))
+	for i := range series {
+		x := &Poly{}
+		x.Abs(&series[i])
+		elements[i] = x
+	}
+	return elements
+}
+
+// Distinct returns the elements with duplicates removed in the order they first appear
+func Distinct(elements []Element) []Element {
+	distinct, seen := make([]Element, 0, len(elements)), make(map[string]bool, len(elements))
+	for _, x := range elements {
+		k := x.Key()
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		distinct = append(distinct, x)
+	}
+	return distinct
+}
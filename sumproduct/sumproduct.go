@@ -0,0 +1,350 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package sumproduct measures the sum-product phenomenon for sets of integers.
+package sumproduct
+
+import (
+	"math"
+	"math/big"
+	"math/bits"
+)
+
+// Max returns the number of unordered pairs, n(n+1)/2, used to normalize the scores
+func Max(length int) int {
+	return (length * (length + 1)) / 2
+}
+
+// Test returns the size of the sumset and the product set of series normalized by Max
+func Test(series []big.Int) (float64, float64) {
+	length := len(series)
+	if length == 0 {
+		return 0, 0
+	}
+	sums, products := Sizes(series)
+	return normalize(length, sums, products)
+}
+
+func normalize(length, sums, products int) (float64, float64) {
+	max := Max(length)
+	sumScore, productScore := float64(sums)/float64(max), float64(products)/float64(max)
+	return sumScore, productScore
+}
+
+// Score combines a sum score and a product score into a single score
+func Score(sum, product float64) float64 {
+	return math.Sqrt(sum*sum + product*product)
+}
+
+// Sizes returns the size of the sumset and the product set of series, values are hashed as
+// machine words when they fit and as big.Ints otherwise
+func Sizes(series []big.Int) (sums, products int) {
+	return fill(series).sizes()
+}
+
+// fill returns the set of series using the smallest representation that fits every value
+func fill(series []big.Int) set {
+	t := small
+	for i := range series {
+		if u := tierOf(&series[i]); u > t {
+			t = u
+		}
+	}
+	set := newSet(t, Max(len(series)))
+	for i := range series {
+		set.add(&series[i])
+	}
+	return set
+}
+
+// Scorer incrementally maintains the sumset and product set of a growing series
+type Scorer struct {
+	values        []big.Int
+	tier          tier
+	set           set
+	pair          Pair
+	first, second *expansion
+}
+
+// NewScorer creates an empty Scorer
+func NewScorer() *Scorer {
+	return NewPairScorer(Default)
+}
+
+// NewPairScorer creates an empty Scorer that also maintains the expanders of pair
+func NewPairScorer(pair Pair) *Scorer {
+	s := &Scorer{
+		values: make([]big.Int, 0, 256),
+		set:    newSet(small, 256),
+		pair:   pair,
+	}
+	if pair != Default {
+		s.first, s.second = newExpansion(pair[0], 256), newExpansion(pair[1], 256)
+	}
+	return s
+}
+
+// Add appends x to the series
+func (s *Scorer) Add(x *big.Int) {
+	if t := tierOf(x); t > s.tier {
+		s.tier, s.set = t, newSet(t, Max(len(s.values)+1))
+		for i := range s.values {
+			s.set.add(&s.values[i])
+		}
+	}
+	value := big.Int{}
+	value.Set(x)
+	s.values = append(s.values, value)
+	s.set.add(x)
+	if s.pair != Default {
+		s.first.add(NewInteger(x))
+		s.second.add(NewInteger(x))
+	}
+}
+
+// Len returns the length of the series
+func (s *Scorer) Len() int {
+	return len(s.values)
+}
+
+// Sizes returns the size of the sumset and the product set of the series
+func (s *Scorer) Sizes() (sums, products int) {
+	return s.set.sizes()
+}
+
+// Score returns the size of the sumset and the product set of the series normalized by Max
+func (s *Scorer) Score() (float64, float64) {
+	if len(s.values) == 0 {
+		return 0, 0
+	}
+	sums, products := s.set.sizes()
+	return normalize(len(s.values), sums, products)
+}
+
+// tier is the representation used to hash sums and products
+type tier int
+
+const (
+	// small values have magnitudes less than 2^31 so sums and products fit in an int64
+	small tier = iota
+	// medium values fit in an int64 so sums and products fit in an int128
+	medium
+	// large values need a big.Int
+	large
+)
+
+func tierOf(x *big.Int) tier {
+	if !x.IsInt64() || x.Int64() == math.MinInt64 {
+		return large
+	}
+	if v := x.Int64(); v > math.MaxInt32 || v < -math.MaxInt32 {
+		return medium
+	}
+	return small
+}
+
+// set is a sumset and product set
+type set interface {
+	// add appends x and adds its sums and products with every value including itself
+	add(x *big.Int)
+	sizes() (int, int)
+	// energies returns the additive and multiplicative energy
+	energies() (int64, int64)
+	// histograms returns the histograms of the representation counts of the sums and products
+	histograms() (map[int]int, map[int]int)
+}
+
+func newSet(t tier, size int) set {
+	switch t {
+	case small:
+		return &set64{sums: make(map[int64]int, size), products: make(map[int64]int, size)}
+	case medium:
+		return &set128{sums: make(map[int128]int, size), products: make(map[int128]int, size)}
+	}
+	return &setBig{sums: make(map[string]int, size), products: make(map[string]int, size)}
+}
+
+// weight is the number of ordered pairs represented by the unordered pair of the values at i and j
+func weight(i, j int) int {
+	if i == j {
+		return 1
+	}
+	return 2
+}
+
+// increase adds w representations of k and returns the increase in energy, (r+w)^2 - r^2
+func increase(counts map[int64]int, k int64, w int) int64 {
+	r := counts[k]
+	counts[k] = r + w
+	return int64(2*r*w + w*w)
+}
+
+// set64 hashes sums and products as int64
+type set64 struct {
+	values                   []int64
+	sums, products           map[int64]int
+	sumEnergy, productEnergy int64
+}
+
+func (s *set64) add(x *big.Int) {
+	v := x.Int64()
+	s.values = append(s.values, v)
+	last := len(s.values) - 1
+	for i, y := range s.values {
+		w := weight(i, last)
+		s.sumEnergy += increase(s.sums, v+y, w)
+		s.productEnergy += increase(s.products, v*y, w)
+	}
+}
+
+func (s *set64) sizes() (int, int) {
+	return len(s.sums), len(s.products)
+}
+
+func (s *set64) energies() (int64, int64) {
+	return s.sumEnergy, s.productEnergy
+}
+
+func (s *set64) histograms() (map[int]int, map[int]int) {
+	sums, products := make(map[int]int), make(map[int]int)
+	for _, r := range s.sums {
+		sums[r]++
+	}
+	for _, r := range s.products {
+		products[r]++
+	}
+	return sums, products
+}
+
+// int128 is a signed 128 bit integer in two's complement
+type int128 struct {
+	hi, lo uint64
+}
+
+func newInt128(v int64) int128 {
+	return int128{hi: uint64(v >> 63), lo: uint64(v)}
+}
+
+func (x int128) add(y int128) int128 {
+	lo, carry := bits.Add64(x.lo, y.lo, 0)
+	hi, _ := bits.Add64(x.hi, y.hi, carry)
+	return int128{hi: hi, lo: lo}
+}
+
+func (x int128) neg() int128 {
+	lo, borrow := bits.Sub64(0, x.lo, 0)
+	hi, _ := bits.Sub64(0, x.hi, borrow)
+	return int128{hi: hi, lo: lo}
+}
+
+// mul128 multiplies x and y, both must be greater than math.MinInt64
+func mul128(x, y int64) int128 {
+	negative := (x < 0) != (y < 0)
+	if x < 0 {
+		x = -x
+	}
+	if y < 0 {
+		y = -y
+	}
+	hi, lo := bits.Mul64(uint64(x), uint64(y))
+	product := int128{hi: hi, lo: lo}
+	if negative {
+		return product.neg()
+	}
+	return product
+}
+
+// increase128 is increase for int128 keys
+func increase128(counts map[int128]int, k int128, w int) int64 {
+	r := counts[k]
+	counts[k] = r + w
+	return int64(2*r*w + w*w)
+}
+
+// set128 hashes sums and products as int128
+type set128 struct {
+	values                   []int64
+	sums, products           map[int128]int
+	sumEnergy, productEnergy int64
+}
+
+func (s *set128) add(x *big.Int) {
+	v := x.Int64()
+	s.values = append(s.values, v)
+	last := len(s.values) - 1
+	for i, y := range s.values {
+		w := weight(i, last)
+		s.sumEnergy += increase128(s.sums, newInt128(v).add(newInt128(y)), w)
+		s.productEnergy += increase128(s.products, mul128(v, y), w)
+	}
+}
+
+func (s *set128) sizes() (int, int) {
+	return len(s.sums), len(s.products)
+}
+
+func (s *set128) energies() (int64, int64) {
+	return s.sumEnergy, s.productEnergy
+}
+
+func (s *set128) histograms() (map[int]int, map[int]int) {
+	sums, products := make(map[int]int), make(map[int]int)
+	for _, r := range s.sums {
+		sums[r]++
+	}
+	for _, r := range s.products {
+		products[r]++
+	}
+	return sums, products
+}
+
+// key returns a hash key for x made of its sign and magnitude
+func key(x *big.Int) string {
+	return string(rune('1'+x.Sign())) + string(x.Bytes())
+}
+
+// increaseBig is increase for big.Int keys
+func increaseBig(counts map[string]int, k string, w int) int64 {
+	r := counts[k]
+	counts[k] = r + w
+	return int64(2*r*w + w*w)
+}
+
+// setBig hashes sums and products as big.Int keys
+type setBig struct {
+	values                   []*big.Int
+	sums, products           map[string]int
+	sumEnergy, productEnergy int64
+	sum, product             big.Int
+}
+
+func (s *setBig) add(x *big.Int) {
+	x = new(big.Int).Set(x)
+	s.values = append(s.values, x)
+	last := len(s.values) - 1
+	for i, y := range s.values {
+		w := weight(i, last)
+		s.sumEnergy += increaseBig(s.sums, key(s.sum.Add(x, y)), w)
+		s.productEnergy += increaseBig(s.products, key(s.product.Mul(x, y)), w)
+	}
+}
+
+func (s *setBig) sizes() (int, int) {
+	return len(s.sums), len(s.products)
+}
+
+func (s *setBig) energies() (int64, int64) {
+	return s.sumEnergy, s.productEnergy
+}
+
+func (s *setBig) histograms() (map[int]int, map[int]int) {
+	sums, products := make(map[int]int), make(map[int]int)
+	for _, r := range s.sums {
+		sums[r]++
+	}
+	for _, r := range s.products {
+		products[r]++
+	}
+	return sums, products
+}
@@ -0,0 +1,274 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sumproduct
+
+import (
+	"math"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/pointlander/collatz/series"
+)
+
+// sumProductTest is the original Test that keys every sum and product by its binary text
+func sumProductTest(series []big.Int) (float64, float64) {
+	length := len(series)
+	if length == 0 {
+		return 0, 0
+	}
+	sums, products := make(map[string]int, length*length), make(map[string]int, length*length)
+	for _, x := range series {
+		for _, y := range series {
+			sum, product := big.Int{}, big.Int{}
+			sum.Add(&x, &y)
+			sums[sum.Text(2)]++
+			product.Mul(&x, &y)
+			products[product.Text(2)]++
+		}
+	}
+	max := Max(length)
+	sumScore, productScore := float64(len(sums))/float64(max), float64(len(products))/float64(max)
+	return sumScore, productScore
+}
+
+func ints(values ...int64) []big.Int {
+	series := make([]big.Int, len(values))
+	for i, v := range values {
+		series[i].SetInt64(v)
+	}
+	return series
+}
+
+func parse(t testing.TB, values ...string) []big.Int {
+	series := make([]big.Int, len(values))
+	for i, v := range values {
+		if _, ok := series[i].SetString(v, 10); !ok {
+			t.Fatalf("invalid number %q", v)
+		}
+	}
+	return series
+}
+
+// check compares Test, Sizes and an incrementally built Scorer to sumProductTest
+func check(t *testing.T, name string, series []big.Int) {
+	t.Helper()
+	sum, product := sumProductTest(series)
+	if s, p := Test(series); s != sum || p != product {
+		t.Errorf("%s: Test = %v, %v, want %v, %v", name, s, p, sum, product)
+	}
+	if len(series) == 0 {
+		return
+	}
+	sums, products := Sizes(series)
+	if s, p := normalize(len(series), sums, products); s != sum || p != product {
+		t.Errorf("%s: Sizes = %d, %d, want scores %v, %v", name, sums, products, sum, product)
+	}
+	scorer := NewScorer()
+	for i := range series {
+		scorer.Add(&series[i])
+		sum, product := sumProductTest(series[:i+1])
+		if s, p := scorer.Score(); s != sum || p != product {
+			t.Errorf("%s: Scorer after %d values = %v, %v, want %v, %v", name, i+1, s, p, sum, product)
+		}
+	}
+}
+
+func TestSizes(t *testing.T) {
+	tests := []struct {
+		name   string
+		series []big.Int
+		tier   tier
+	}{
+		{"empty", nil, small},
+		{"one", ints(1), small},
+		{"zero", ints(0, 0, 0), small},
+		{"arithmetic", ints(1, 2, 3, 4, 5, 6, 7, 8), small},
+		{"geometric", ints(1, 2, 4, 8, 16, 32, 64), small},
+		{"negative", ints(-3, -1, 0, 1, 3), small},
+		{"int32", ints(math.MaxInt32, -math.MaxInt32, 1, -1), small},
+		{"medium", ints(math.MaxInt32+1, 2, 3), medium},
+		{"int64", ints(math.MaxInt64, math.MaxInt64-1, -math.MaxInt64, 1, -1, 0), medium},
+		{"overflow", ints(1<<62, 1<<62, 3<<61, -(1 << 62)), medium},
+		{"min int64", ints(math.MinInt64, math.MaxInt64, -1), large},
+		{"large", parse(t, "18446744073709551616", "-18446744073709551616", "1", "-1"), large},
+		{"huge", parse(t, "340282366920938463463374607431768211457", "-340282366920938463463374607431768211457",
+			"-99999999999999999999999999", "2"), large},
+	}
+	for _, test := range tests {
+		if len(test.series) > 0 {
+			if set := fill(test.series); tierOfSet(set) != test.tier {
+				t.Errorf("%s: tier = %d, want %d", test.name, tierOfSet(set), test.tier)
+			}
+		}
+		check(t, test.name, test.series)
+	}
+}
+
+func tierOfSet(s set) tier {
+	switch s.(type) {
+	case *set64:
+		return small
+	case *set128:
+		return medium
+	}
+	return large
+}
+
+func TestSizesRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	values := map[string]func() *big.Int{
+		"small": func() *big.Int {
+			return big.NewInt(rng.Int63n(1<<32) - 1<<31)
+		},
+		"small and few": func() *big.Int {
+			return big.NewInt(rng.Int63n(32) - 16)
+		},
+		"medium": func() *big.Int {
+			v := rng.Int63()
+			if rng.Intn(2) == 0 {
+				v = -v
+			}
+			return big.NewInt(v)
+		},
+		"large": func() *big.Int {
+			v := new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), 130))
+			if rng.Intn(2) == 0 {
+				v.Neg(v)
+			}
+			return v
+		},
+	}
+	kinds := []string{"small", "small and few", "medium", "large"}
+	for i := 0; i < 200; i++ {
+		// mix every kind of value up to the current one so that the scorer is promoted mid series
+		kind := kinds[i%len(kinds)]
+		series := make([]big.Int, 1+rng.Intn(40))
+		for j := range series {
+			series[j].Set(values[kinds[rng.Intn(i%len(kinds)+1)]]())
+			if j > 0 && rng.Intn(8) == 0 {
+				series[j].Set(&series[rng.Intn(j)])
+			}
+		}
+		check(t, kind, series)
+	}
+}
+
+func benchmark(b *testing.B, score func(series []big.Int)) {
+	numbers := series.SevenSmoothComplement(2048)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		score(numbers)
+	}
+}
+
+func BenchmarkReference(b *testing.B) {
+	benchmark(b, func(series []big.Int) {
+		sumProductTest(series)
+	})
+}
+
+func BenchmarkSizes(b *testing.B) {
+	benchmark(b, func(series []big.Int) {
+		Sizes(series)
+	})
+}
+
+func BenchmarkScorer(b *testing.B) {
+	benchmark(b, func(series []big.Int) {
+		scorer := NewScorer()
+		for i := range series {
+			scorer.Add(&series[i])
+		}
+		scorer.Score()
+	})
+}
+
+// composition returns the size of {a∘(b⋄c)} by forming every triple as rationals
+func composition(series []big.Int, outer, inner Expander) int {
+	operate := func(op Expander, x, y *big.Rat) *big.Rat {
+		z := new(big.Rat)
+		switch op {
+		case Sum:
+			return z.Add(x, y)
+		case Product:
+			return z.Mul(x, y)
+		case Difference:
+			return z.Sub(x, y)
+		}
+		if y.Sign() == 0 {
+			return nil
+		}
+		return z.Quo(x, y)
+	}
+	values := make([]*big.Rat, len(series))
+	for i := range series {
+		values[i] = new(big.Rat).SetInt(&series[i])
+	}
+	seen := make(map[string]bool)
+	for _, x := range values {
+		for _, y := range values {
+			for _, z := range values {
+				if w := operate(inner, y, z); w != nil {
+					if w = operate(outer, x, w); w != nil {
+						seen[w.RatString()] = true
+					}
+				}
+			}
+		}
+	}
+	return len(seen)
+}
+
+func TestCompose(t *testing.T) {
+	operations := []Expander{Sum, Product, Difference, Quotient}
+	tests := [][]big.Int{
+		ints(1, 2, 3, 4, 5, 6),
+		ints(1, 2, 4, 8, 16),
+		ints(-2, 0, 3, 3, 7),
+	}
+	for _, outer := range operations {
+		for _, inner := range operations {
+			expander, err := Compose(outer, inner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if parsed, err := ParseExpander(expander.String()); err != nil || parsed != expander {
+				t.Errorf("ParseExpander(%q) = %v, %v", expander.String(), parsed, err)
+			}
+			for _, series := range tests {
+				measure, err := NewRingMeasure(Distinct(Integers(series)), Pair{expander, Product})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := composition(series, outer, inner); measure.Sums != want {
+					t.Errorf("%v of %v = %d, want %d", expander, series, measure.Sums, want)
+				}
+			}
+		}
+	}
+	if _, err := Compose(Shifted, Sum); err == nil {
+		t.Error("composed the shifted expander")
+	}
+}
+
+func TestRingCheck(t *testing.T) {
+	quotient := Pair{Difference, Quotient}
+	for name, divides := range map[string]bool{"integer": true, "rational": true, "gaussian": false, "gf2": false} {
+		ring, err := ParseRing(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ring.Check(quotient); (err == nil) != divides {
+			t.Errorf("%s: Check(%v) = %v", name, quotient, err)
+		}
+		if err := ring.Check(Default); err != nil {
+			t.Errorf("%s: Check(%v) = %v", name, Default, err)
+		}
+	}
+	if _, err := NewPairMeasure(ints(1, 2, 3), quotient); err != nil {
+		t.Error(err)
+	}
+}
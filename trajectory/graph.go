@@ -0,0 +1,176 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/pointlander/collatz/primes"
+)
+
+// GraphNode is a value in a graph of merged trajectories
+type GraphNode struct {
+	Value big.Int
+	// Next is the index of the node Value maps to, -1 if the trajectory ends at Value
+	Next int
+	// TotalStoppingTime is the number of steps from Value to 1
+	TotalStoppingTime int
+	Factors           []big.Int
+}
+
+// Graph is the union of trajectories as a directed graph
+type Graph struct {
+	Nodes []GraphNode
+	// Factor enables factoring the values added to the graph
+	Factor bool
+	index  map[string]int
+}
+
+// NewGraph creates an empty graph
+func NewGraph() *Graph {
+	return &Graph{
+		Nodes: make([]GraphNode, 0, 256),
+		index: make(map[string]int),
+	}
+}
+
+// Contains returns true if value is a node of the graph
+func (g *Graph) Contains(value *big.Int) bool {
+	_, ok := g.index[value.String()]
+	return ok
+}
+
+// Add merges a trajectory ending in 1, as returned by Collatz, into the graph
+func (g *Graph) Add(series []big.Int) error {
+	next := -1
+	for i := len(series) - 1; i >= 0; i-- {
+		key := series[i].String()
+		if index, ok := g.index[key]; ok {
+			next = index
+			continue
+		}
+		node := GraphNode{
+			Next:              next,
+			TotalStoppingTime: len(series) - 1 - i,
+		}
+		if g.Factor {
+			factors, err := primes.Factor(&series[i])
+			if err != nil {
+				return err
+			}
+			node.Factors = factors
+		}
+		node.Value.Set(&series[i])
+		next = len(g.Nodes)
+		g.index[key] = next
+		g.Nodes = append(g.Nodes, node)
+	}
+	return nil
+}
+
+// factors formats the factors of a node as a space separated list
+func (n *GraphNode) factors() string {
+	factors := make([]string, len(n.Factors))
+	for i := range n.Factors {
+		factors[i] = n.Factors[i].String()
+	}
+	return strings.Join(factors, " ")
+}
+
+// WriteDOT writes the graph in the Graphviz DOT format
+func (g *Graph) WriteDOT(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "digraph collatz {\n")
+	for i := range g.Nodes {
+		node := &g.Nodes[i]
+		fmt.Fprintf(out, "\tn%v [label=\"%v\", factors=\"%s\", total_stopping_time=%d];\n",
+			&node.Value, &node.Value, node.factors(), node.TotalStoppingTime)
+	}
+	for i := range g.Nodes {
+		node := &g.Nodes[i]
+		if node.Next >= 0 {
+			fmt.Fprintf(out, "\tn%v -> n%v;\n", &node.Value, &g.Nodes[node.Next].Value)
+		}
+	}
+	fmt.Fprintf(out, "}\n")
+	return out.Flush()
+}
+
+// WriteGraphML writes the graph in the GraphML format
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(out, "<graphml xmlns=\"http://graphml.graphdrawing.org/xmlns\">\n")
+	fmt.Fprintf(out, "  <key id=\"value\" for=\"node\" attr.name=\"value\" attr.type=\"string\"/>\n")
+	fmt.Fprintf(out, "  <key id=\"factors\" for=\"node\" attr.name=\"factors\" attr.type=\"string\"/>\n")
+	fmt.Fprintf(out, "  <key id=\"total_stopping_time\" for=\"node\" attr.name=\"total_stopping_time\" attr.type=\"int\"/>\n")
+	fmt.Fprintf(out, "  <graph id=\"collatz\" edgedefault=\"directed\">\n")
+	for i := range g.Nodes {
+		node := &g.Nodes[i]
+		fmt.Fprintf(out, "    <node id=\"%v\">\n", &node.Value)
+		fmt.Fprintf(out, "      <data key=\"value\">%v</data>\n", &node.Value)
+		fmt.Fprintf(out, "      <data key=\"factors\">%s</data>\n", node.factors())
+		fmt.Fprintf(out, "      <data key=\"total_stopping_time\">%d</data>\n", node.TotalStoppingTime)
+		fmt.Fprintf(out, "    </node>\n")
+	}
+	for i := range g.Nodes {
+		node := &g.Nodes[i]
+		if node.Next >= 0 {
+			fmt.Fprintf(out, "    <edge source=\"%v\" target=\"%v\"/>\n", &node.Value, &g.Nodes[node.Next].Value)
+		}
+	}
+	fmt.Fprintf(out, "  </graph>\n")
+	fmt.Fprintf(out, "</graphml>\n")
+	return out.Flush()
+}
+
+// WriteJSON writes the graph in the node-link JSON format used by networkx
+func (g *Graph) WriteJSON(w io.Writer) error {
+	type Node struct {
+		ID                string `json:"id"`
+		Value             string `json:"value"`
+		Factors           string `json:"factors"`
+		TotalStoppingTime int    `json:"total_stopping_time"`
+	}
+	type Link struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+	}
+	type NodeLink struct {
+		Directed   bool              `json:"directed"`
+		Multigraph bool              `json:"multigraph"`
+		Graph      map[string]string `json:"graph"`
+		Nodes      []Node            `json:"nodes"`
+		Links      []Link            `json:"links"`
+	}
+	graph := NodeLink{
+		Directed: true,
+		Graph:    map[string]string{},
+		Nodes:    make([]Node, 0, len(g.Nodes)),
+		Links:    make([]Link, 0, len(g.Nodes)),
+	}
+	for i := range g.Nodes {
+		node := &g.Nodes[i]
+		value := node.Value.String()
+		graph.Nodes = append(graph.Nodes, Node{
+			ID:                value,
+			Value:             value,
+			Factors:           node.factors(),
+			TotalStoppingTime: node.TotalStoppingTime,
+		})
+		if node.Next >= 0 {
+			graph.Links = append(graph.Links, Link{
+				Source: value,
+				Target: g.Nodes[node.Next].Value.String(),
+			})
+		}
+	}
+	return json.NewEncoder(w).Encode(graph)
+}
@@ -0,0 +1,112 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+var (
+	three = big.NewInt(3)
+	four  = big.NewInt(4)
+	six   = big.NewInt(6)
+)
+
+// Predecessors returns the values that map to n in one 3x+1 step, 2n and (n-1)/3 when n = 4 mod 6
+func Predecessors(n *big.Int) []big.Int {
+	predecessors := make([]big.Int, 1, 2)
+	predecessors[0].Lsh(n, 1)
+	residue := big.Int{}
+	if residue.Mod(n, six).Cmp(four) == 0 {
+		x := big.Int{}
+		x.Sub(n, one).Quo(&x, three)
+		predecessors = append(predecessors, x)
+	}
+	return predecessors
+}
+
+// Node is a node of an inverse tree
+type Node struct {
+	Value big.Int
+	// Parent is the index of the node Value maps to, -1 for the root
+	Parent int
+	Level  int
+}
+
+// Tree is the tree of values that reach a target, ordered breadth first
+type Tree struct {
+	Nodes []Node
+	// Levels[k] is the index of the first node at level k, with a final entry of len(Nodes)
+	Levels []int
+}
+
+// InverseTree enumerates every value that reaches target within k steps, each value appears once
+// at the first level it is found so the trivial cycle is not followed
+func InverseTree(target *big.Int, k int) (*Tree, error) {
+	if target.Sign() <= 0 {
+		return nil, ErrNotPositive
+	}
+	if k < 0 {
+		return nil, fmt.Errorf("trajectory: negative depth %d", k)
+	}
+	tree := &Tree{
+		Nodes:  make([]Node, 1, 256),
+		Levels: make([]int, 0, k+2),
+	}
+	tree.Nodes[0].Value.Set(target)
+	tree.Nodes[0].Parent = -1
+	tree.Levels = append(tree.Levels, 0)
+	visited := map[string]bool{target.String(): true}
+	for level := 1; level <= k; level++ {
+		start, end := tree.Levels[level-1], len(tree.Nodes)
+		tree.Levels = append(tree.Levels, end)
+		for parent := start; parent < end; parent++ {
+			for _, value := range Predecessors(&tree.Nodes[parent].Value) {
+				key := value.String()
+				if visited[key] {
+					continue
+				}
+				visited[key] = true
+				tree.Nodes = append(tree.Nodes, Node{
+					Value:  value,
+					Parent: parent,
+					Level:  level,
+				})
+			}
+		}
+	}
+	tree.Levels = append(tree.Levels, len(tree.Nodes))
+	return tree, nil
+}
+
+// Depth returns the number of levels below the root
+func (t *Tree) Depth() int {
+	return len(t.Levels) - 2
+}
+
+// Level returns the nodes at level k
+func (t *Tree) Level(k int) []Node {
+	return t.Nodes[t.Levels[k]:t.Levels[k+1]]
+}
+
+// WriteDOT writes the tree in the Graphviz DOT format with edges in the direction of the map
+func (t *Tree) WriteDOT(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "digraph inverse {\n")
+	for i := range t.Nodes {
+		node := &t.Nodes[i]
+		fmt.Fprintf(out, "\tn%d [label=\"%v\"];\n", i, &node.Value)
+	}
+	for i := range t.Nodes {
+		if parent := t.Nodes[i].Parent; parent >= 0 {
+			fmt.Fprintf(out, "\tn%d -> n%d;\n", i, parent)
+		}
+	}
+	fmt.Fprintf(out, "}\n")
+	return out.Flush()
+}
@@ -0,0 +1,146 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Rule is the affine map x -> (Multiplier*x + Addend)/Divisor
+type Rule struct {
+	Multiplier, Addend, Divisor int64
+}
+
+// Map is a generalized Collatz map where Rules[r] is applied to x when x = r mod Modulus
+type Map struct {
+	Modulus int64
+	Rules   []Rule
+}
+
+// Standard is the 3x+1 map
+var Standard = QX(3, 1)
+
+// QX returns the map that halves even numbers and sends odd x to q*x + r
+func QX(q, r int64) Map {
+	return Map{
+		Modulus: 2,
+		Rules: []Rule{
+			{Multiplier: 1, Addend: 0, Divisor: 2},
+			{Multiplier: q, Addend: r, Divisor: 1},
+		},
+	}
+}
+
+// Validate checks that every rule maps its residue class to integers
+func (m Map) Validate() error {
+	if m.Modulus < 1 {
+		return errors.New("trajectory: modulus must be positive")
+	}
+	if int64(len(m.Rules)) != m.Modulus {
+		return fmt.Errorf("trajectory: %d rules for modulus %d", len(m.Rules), m.Modulus)
+	}
+	for r, rule := range m.Rules {
+		if rule.Divisor == 0 {
+			return fmt.Errorf("trajectory: rule %d has a zero divisor", r)
+		}
+		if (rule.Multiplier*int64(r)+rule.Addend)%rule.Divisor != 0 ||
+			(rule.Multiplier*m.Modulus)%rule.Divisor != 0 {
+			return fmt.Errorf("trajectory: rule %d does not map residue %d mod %d to integers", r, r, m.Modulus)
+		}
+	}
+	return nil
+}
+
+// Step applies the map to x in place
+func (m Map) Step(x *big.Int) {
+	modulus, residue := big.NewInt(m.Modulus), big.Int{}
+	residue.Mod(x, modulus)
+	rule := m.Rules[residue.Int64()]
+	if rule.Multiplier != 1 {
+		x.Mul(x, big.NewInt(rule.Multiplier))
+	}
+	if rule.Addend != 0 {
+		x.Add(x, big.NewInt(rule.Addend))
+	}
+	if rule.Divisor != 1 {
+		x.Quo(x, big.NewInt(rule.Divisor))
+	}
+}
+
+// Trajectory returns the trajectory of n under the map down to and including 1
+// or an error if the trajectory cycles or exceeds DefaultLimits
+func (m Map) Trajectory(n *big.Int) ([]big.Int, error) {
+	result, err := m.Run(n, DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	switch result.Status {
+	case StatusCycle:
+		return nil, ErrCycle
+	case StatusExceeded:
+		return nil, ErrExceeded
+	}
+	return result.Series, nil
+}
+
+// String formats the map in the form accepted by ParseMap
+func (m Map) String() string {
+	rules := make([]string, len(m.Rules))
+	for i, rule := range m.Rules {
+		rules[i] = fmt.Sprintf("%d,%d,%d", rule.Multiplier, rule.Addend, rule.Divisor)
+	}
+	return fmt.Sprintf("%d:%s", m.Modulus, strings.Join(rules, ";"))
+}
+
+// ParseMap parses either a qx+r map such as "5x+1" or "3x-1", or a residue map
+// such as "2:1,0,2;3,1,1" listing the multiplier, addend and divisor for each residue
+func ParseMap(s string) (Map, error) {
+	s = strings.ReplaceAll(s, " ", "")
+	if modulus, rules, ok := strings.Cut(s, ":"); ok {
+		m := Map{}
+		var err error
+		m.Modulus, err = strconv.ParseInt(modulus, 10, 64)
+		if err != nil {
+			return Map{}, fmt.Errorf("trajectory: invalid modulus %q: %w", modulus, err)
+		}
+		for _, rule := range strings.Split(rules, ";") {
+			parts := strings.Split(rule, ",")
+			if len(parts) != 3 {
+				return Map{}, fmt.Errorf("trajectory: invalid rule %q", rule)
+			}
+			var values [3]int64
+			for i, part := range parts {
+				values[i], err = strconv.ParseInt(part, 10, 64)
+				if err != nil {
+					return Map{}, fmt.Errorf("trajectory: invalid rule %q: %w", rule, err)
+				}
+			}
+			m.Rules = append(m.Rules, Rule{Multiplier: values[0], Addend: values[1], Divisor: values[2]})
+		}
+		return m, m.Validate()
+	}
+
+	q, r, ok := strings.Cut(s, "x")
+	if !ok {
+		return Map{}, fmt.Errorf("trajectory: invalid map %q", s)
+	}
+	multiplier, err := strconv.ParseInt(q, 10, 64)
+	if err != nil {
+		return Map{}, fmt.Errorf("trajectory: invalid multiplier %q: %w", q, err)
+	}
+	var addend int64
+	if r != "" {
+		addend, err = strconv.ParseInt(r, 10, 64)
+		if err != nil {
+			return Map{}, fmt.Errorf("trajectory: invalid addend %q: %w", r, err)
+		}
+	}
+	m := QX(multiplier, addend)
+	return m, m.Validate()
+}
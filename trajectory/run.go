@@ -0,0 +1,107 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"errors"
+	"math/big"
+)
+
+var (
+	// ErrCycle is returned when a trajectory enters a cycle that does not contain 1
+	ErrCycle = errors.New("trajectory: entered cycle")
+	// ErrExceeded is returned when a trajectory exceeds its limits
+	ErrExceeded = errors.New("trajectory: budget exceeded")
+)
+
+// Status is the outcome of running a trajectory
+type Status int
+
+const (
+	// StatusOne means the trajectory reached 1
+	StatusOne Status = iota
+	// StatusCycle means the trajectory entered a cycle that does not contain 1
+	StatusCycle
+	// StatusExceeded means the trajectory exceeded its step or bit length budget
+	StatusExceeded
+)
+
+// String returns a description of the status
+func (s Status) String() string {
+	switch s {
+	case StatusOne:
+		return "reached 1"
+	case StatusCycle:
+		return "entered cycle"
+	case StatusExceeded:
+		return "budget exceeded"
+	}
+	return "unknown"
+}
+
+// Limits bounds the work done computing a trajectory, zero means unlimited
+type Limits struct {
+	MaxSteps int
+	MaxBits  int
+}
+
+// DefaultLimits are the limits used by Trajectory
+var DefaultLimits = Limits{
+	MaxSteps: 1 << 20,
+	MaxBits:  1 << 14,
+}
+
+// Result is the result of running a trajectory
+type Result struct {
+	Status Status
+	// Series is the trajectory, when a cycle is entered it ends with the first repeated value
+	Series []big.Int
+	// Cycle holds the members of the cycle in the order they are visited
+	Cycle []big.Int
+}
+
+// Run computes the trajectory of n under the map, using Brent's algorithm to detect cycles
+func (m Map) Run(n *big.Int, limits Limits) (Result, error) {
+	if err := m.Validate(); err != nil {
+		return Result{}, err
+	}
+	i, tortoise := big.Int{}, big.Int{}
+	i.Set(n)
+	tortoise.Set(n)
+	series := make([]big.Int, 0, 256)
+	cp := func() (z big.Int) {
+		z.Set(&i)
+		return z
+	}
+	series = append(series, cp())
+	power, lam := 1, 0
+	for one.Cmp(&i) != 0 {
+		if (limits.MaxSteps > 0 && len(series) > limits.MaxSteps) ||
+			(limits.MaxBits > 0 && i.BitLen() > limits.MaxBits) {
+			return Result{Status: StatusExceeded, Series: series}, nil
+		}
+		m.Step(&i)
+		series = append(series, cp())
+		lam++
+		if tortoise.Cmp(&i) == 0 {
+			mu := 0
+			for series[mu].Cmp(&series[mu+lam]) != 0 {
+				mu++
+			}
+			return Result{
+				Status: StatusCycle,
+				Series: series[:mu+lam+1],
+				Cycle:  series[mu : mu+lam],
+			}, nil
+		}
+		if lam == power {
+			tortoise.Set(&i)
+			power *= 2
+			lam = 0
+		}
+	}
+
+	return Result{Status: StatusOne, Series: series}, nil
+}
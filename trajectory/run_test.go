@@ -0,0 +1,97 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"math/big"
+	"testing"
+)
+
+func values(series []big.Int) []int64 {
+	v := make([]int64, len(series))
+	for i := range series {
+		v[i] = series[i].Int64()
+	}
+	return v
+}
+
+func equalInts(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRun(t *testing.T) {
+	minus := QX(3, -1)
+	tests := []struct {
+		name   string
+		m      Map
+		n      int64
+		limits Limits
+		status Status
+		cycle  []int64
+		// length is the length of the series, zero to skip the check
+		length int
+	}{
+		{"3x+1 of 1", Standard, 1, DefaultLimits, StatusOne, nil, 1},
+		{"3x+1 of 27", Standard, 27, DefaultLimits, StatusOne, nil, 112},
+		{"3x-1 of 5", minus, 5, DefaultLimits, StatusCycle, []int64{5, 14, 7, 20, 10}, 6},
+		{"3x-1 of 7", minus, 7, DefaultLimits, StatusCycle, []int64{7, 20, 10, 5, 14}, 6},
+		{"3x-1 of 17", minus, 17, DefaultLimits, StatusCycle,
+			[]int64{17, 50, 25, 74, 37, 110, 55, 164, 82, 41, 122, 61, 182, 91, 272, 136, 68, 34}, 19},
+		{"3x-1 of 3", minus, 3, DefaultLimits, StatusOne, nil, 5},
+		{"3x+1 of 0", Standard, 0, DefaultLimits, StatusCycle, []int64{0}, 2},
+		{"3x+1 of -1", Standard, -1, DefaultLimits, StatusCycle, []int64{-1, -2}, 3},
+		{"3x+1 of -5", Standard, -5, DefaultLimits, StatusCycle, []int64{-5, -14, -7, -20, -10}, 6},
+		{"3x+1 of -17", Standard, -17, DefaultLimits, StatusCycle,
+			[]int64{-17, -50, -25, -74, -37, -110, -55, -164, -82, -41, -122, -61, -182, -91, -272, -136, -68, -34}, 19},
+		{"steps", Standard, 27, Limits{MaxSteps: 10}, StatusExceeded, nil, 11},
+		{"steps enough", Standard, 27, Limits{MaxSteps: 111}, StatusOne, nil, 112},
+		{"bits", Standard, 27, Limits{MaxBits: 4}, StatusExceeded, nil, 1},
+		{"bits on the way", Standard, 27, Limits{MaxBits: 8}, StatusExceeded, nil, 0},
+	}
+	for _, test := range tests {
+		result, err := test.m.Run(big.NewInt(test.n), test.limits)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if result.Status != test.status {
+			t.Errorf("%s: status = %v, want %v", test.name, result.Status, test.status)
+		}
+		if cycle := values(result.Cycle); !equalInts(cycle, test.cycle) {
+			t.Errorf("%s: cycle = %v, want %v", test.name, cycle, test.cycle)
+		}
+		if test.length > 0 && len(result.Series) != test.length {
+			t.Errorf("%s: %d values, want %d", test.name, len(result.Series), test.length)
+		}
+		if series := result.Series; len(series) == 0 || series[0].Int64() != test.n {
+			t.Errorf("%s: series does not start with %d", test.name, test.n)
+		}
+		if test.status == StatusCycle {
+			last := result.Series[len(result.Series)-1]
+			if last.Cmp(&result.Cycle[0]) != 0 {
+				t.Errorf("%s: series ends with %v instead of the first repeated value", test.name, &last)
+			}
+		}
+		if test.limits.MaxBits > 0 && test.status == StatusExceeded {
+			last := result.Series[len(result.Series)-1]
+			if last.BitLen() <= test.limits.MaxBits {
+				t.Errorf("%s: stopped at %v within %d bits", test.name, &last, test.limits.MaxBits)
+			}
+		}
+	}
+}
+
+func TestRunInvalid(t *testing.T) {
+	if _, err := (Map{Modulus: 2}).Run(big.NewInt(1), DefaultLimits); err == nil {
+		t.Error("ran a map without rules")
+	}
+}
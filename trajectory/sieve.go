@@ -0,0 +1,54 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"fmt"
+)
+
+// MaxSieveBits is the largest supported sieve modulus exponent
+const MaxSieveBits = 24
+
+// Sieve records the residue classes mod 2^Bits whose members provably drop below themselves
+// within Bits halvings of the 3x+1 map
+type Sieve struct {
+	Bits int
+	// thresholds[r] is the smallest n = r mod 2^Bits known to drop, zero if the class must be checked
+	thresholds []uint64
+}
+
+// NewSieve computes the sieve for residues mod 2^bits
+func NewSieve(bits int) (*Sieve, error) {
+	if bits < 1 || bits > MaxSieveBits {
+		return nil, fmt.Errorf("trajectory: sieve bits must be in [1, %d]", MaxSieveBits)
+	}
+	s := &Sieve{
+		Bits:       bits,
+		thresholds: make([]uint64, 1<<uint(bits)),
+	}
+	for r := range s.thresholds {
+		// x = (a*n + c) / 2^j for every n = r mod 2^bits
+		a, c, j := uint64(1), uint64(0), 0
+		for j < bits {
+			x := (a*uint64(r) + c) >> uint(j)
+			if x&1 == 1 {
+				a, c = 3*a, 3*c+1<<uint(j)
+			} else {
+				j++
+			}
+			if a < 1<<uint(j) {
+				s.thresholds[r] = c/(1<<uint(j)-a) + 1
+				break
+			}
+		}
+	}
+	return s, nil
+}
+
+// Skip returns true if n provably drops below itself
+func (s *Sieve) Skip(n uint64) bool {
+	threshold := s.thresholds[n&(1<<uint(s.Bits)-1)]
+	return threshold != 0 && n >= threshold
+}
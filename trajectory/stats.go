@@ -0,0 +1,84 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"math/big"
+	"strings"
+)
+
+// ParityVector is the sequence of parities, 0 or 1, visited by the shortcut map T(x) = (3x+1)/2 or x/2
+type ParityVector []byte
+
+// String returns the parity vector as a string of 0s and 1s
+func (p ParityVector) String() string {
+	var s strings.Builder
+	for _, bit := range p {
+		s.WriteByte('0' + bit)
+	}
+	return s.String()
+}
+
+// Stats are statistics of a 3x+1 trajectory
+type Stats struct {
+	// Start is the starting value
+	Start big.Int
+	// TotalStoppingTime is the number of steps needed to reach 1
+	TotalStoppingTime int
+	// StoppingTime is the number of steps of the shortcut map T needed to drop below Start,
+	// zero if the trajectory never drops below Start
+	StoppingTime int
+	// Glide is the number of steps needed to drop below Start, zero if the trajectory never
+	// drops below Start
+	Glide int
+	// MaxExcursion is the largest value in the trajectory and MaxStep is the step it first occurs at
+	MaxExcursion big.Int
+	MaxStep      int
+	// OddSteps is the number of 3x+1 steps
+	OddSteps int
+	// Parity is the parity vector of the trajectory under the shortcut map T down to 1
+	Parity ParityVector
+}
+
+// NewStats computes the statistics of a 3x+1 trajectory as returned by Collatz
+func NewStats(series []big.Int) Stats {
+	stats := Stats{}
+	if len(series) == 0 {
+		return stats
+	}
+	start := &series[0]
+	stats.Start.Set(start)
+	stats.TotalStoppingTime = len(series) - 1
+	stats.MaxExcursion.Set(start)
+	for k := range series {
+		x := &series[k]
+		if x.Cmp(&stats.MaxExcursion) > 0 {
+			stats.MaxExcursion.Set(x)
+			stats.MaxStep = k
+		}
+		if stats.Glide == 0 && x.Cmp(start) < 0 {
+			stats.Glide = k
+		}
+	}
+
+	parity := make(ParityVector, 0, len(series))
+	for k, steps := 0, 0; k < len(series)-1; {
+		bit := byte(series[k].Bit(0))
+		parity = append(parity, bit)
+		if bit == 1 {
+			stats.OddSteps++
+			k += 2
+		} else {
+			k++
+		}
+		steps++
+		if stats.StoppingTime == 0 && k < len(series) && series[k].Cmp(start) < 0 {
+			stats.StoppingTime = steps
+		}
+	}
+	stats.Parity = parity
+
+	return stats
+}
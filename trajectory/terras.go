@@ -0,0 +1,81 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// MaxTerrasBits is the largest k for which Classes enumerates the residues mod 2^k
+const MaxTerrasBits = 24
+
+// Parity returns the first k parities of n under the shortcut map T(x) = (3x+1)/2 or x/2
+func Parity(n *big.Int, k int) ParityVector {
+	parity, x := make(ParityVector, k), big.Int{}
+	x.Set(n)
+	for i := range parity {
+		if x.Bit(0) == 1 {
+			parity[i] = 1
+			x.Mul(&x, three).Add(&x, one)
+		}
+		x.Rsh(&x, 1)
+	}
+	return parity
+}
+
+// Residue returns the unique residue r mod 2^len(p) such that every n = r mod 2^len(p) has parity vector p
+func Residue(p ParityVector) (*big.Int, error) {
+	// T^i(n) = (a*n + c) / 2^i where a = 3^(odd steps so far)
+	r, a, c, x := big.NewInt(0), big.NewInt(1), big.NewInt(0), big.Int{}
+	for i, bit := range p {
+		if bit > 1 {
+			return nil, fmt.Errorf("trajectory: invalid parity %d at %d", bit, i)
+		}
+		if x.Mul(a, r).Add(&x, c).Rsh(&x, uint(i)).Bit(0) != uint(bit) {
+			r.SetBit(r, i, 1)
+		}
+		if bit == 1 {
+			a.Mul(a, three)
+			c.Mul(c, three).Add(c, x.Lsh(one, uint(i)))
+		}
+	}
+	return r, nil
+}
+
+// Class is a residue class mod 2^k and its parity vector
+type Class struct {
+	Residue uint64
+	Parity  ParityVector
+	// Odd is the number of odd steps in the parity vector
+	Odd int
+	// Coefficient is 3^Odd / 2^k, the growth of T^k on the class
+	Coefficient float64
+	// Descends is true if the members of the class, other than a few small ones, drop below
+	// themselves within k steps
+	Descends bool
+}
+
+// Classes returns the 2^k residue classes mod 2^k
+func Classes(k int) ([]Class, error) {
+	if k < 1 || k > MaxTerrasBits {
+		return nil, fmt.Errorf("trajectory: k must be in [1, %d]", MaxTerrasBits)
+	}
+	classes, n := make([]Class, 1<<uint(k)), big.Int{}
+	for r := range classes {
+		class := &classes[r]
+		class.Residue = uint64(r)
+		class.Parity = Parity(n.SetUint64(uint64(r)), k)
+		for i, bit := range class.Parity {
+			class.Odd += int(bit)
+			if !class.Descends && math.Pow(3, float64(class.Odd)) < math.Pow(2, float64(i+1)) {
+				class.Descends = true
+			}
+		}
+		class.Coefficient = math.Pow(3, float64(class.Odd)) / math.Pow(2, float64(k))
+	}
+	return classes, nil
+}
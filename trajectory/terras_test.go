@@ -0,0 +1,46 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+func TestResidue(t *testing.T) {
+	for k := 1; k <= 10; k++ {
+		modulus := new(big.Int).Lsh(one, uint(k))
+		seen := make(map[uint64]bool)
+		for v := 0; v < 1<<uint(k); v++ {
+			p := make(ParityVector, k)
+			for i := range p {
+				p[i] = byte(v>>uint(i)) & 1
+			}
+			r, err := Residue(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Sign() < 0 || r.Cmp(modulus) >= 0 {
+				t.Fatalf("Residue(%v) = %v is not a residue mod %v", p, r, modulus)
+			}
+			seen[r.Uint64()] = true
+			// every member of the class has the parity vector
+			n := new(big.Int).Set(r)
+			for m := 0; m < 3; m++ {
+				if got := Parity(n, k); !bytes.Equal(got, p) {
+					t.Fatalf("Parity(%v, %d) = %v, want %v", n, k, got, p)
+				}
+				n.Add(n, modulus)
+			}
+		}
+		if len(seen) != 1<<uint(k) {
+			t.Errorf("k = %d: %d distinct residues, want %d", k, len(seen), 1<<uint(k))
+		}
+	}
+	if _, err := Residue(ParityVector{1, 0, 2}); err == nil {
+		t.Error("Residue of an invalid parity vector succeeded")
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package trajectory computes Collatz trajectories.
+package trajectory
+
+import (
+	"errors"
+	"math/big"
+)
+
+var one = big.NewInt(1)
+
+// ErrNotPositive is returned for starting values that are less than one
+var ErrNotPositive = errors.New("trajectory: starting value must be positive")
+
+// Collatz returns the 3x+1 trajectory of n down to and including 1, values are computed
+// in 128 bit machine words and only fall back to big.Int when they outgrow 128 bits
+func Collatz(n *big.Int) ([]big.Int, error) {
+	if n.Sign() <= 0 {
+		return nil, ErrNotPositive
+	}
+	if n.BitLen() > 128 {
+		series := make([]big.Int, 1, 256)
+		series[0].Set(n)
+		return collatzBig(series), nil
+	}
+
+	x := newUint128(n)
+	values := make([]uint128, 0, 256)
+	values = append(values, x)
+	for !x.isOne() {
+		if x.lo&1 == 0 {
+			x = x.half()
+		} else {
+			y, ok := x.triple()
+			if !ok {
+				return collatzBig(toBig(values)), nil
+			}
+			x = y
+		}
+		values = append(values, x)
+	}
+
+	return toBig(values), nil
+}
+
+// collatzBig continues the trajectory from the last value in series using big.Int arithmetic
+func collatzBig(series []big.Int) []big.Int {
+	i := big.Int{}
+	i.Set(&series[len(series)-1])
+	cp := func() (z big.Int) {
+		z.Set(&i)
+		return z
+	}
+	for one.Cmp(&i) != 0 {
+		if i.Bit(0) == 0 {
+			i.Rsh(&i, 1)
+		} else {
+			z := cp()
+			i.Lsh(&i, 1).SetBit(&i, 0, 1).Add(&i, &z)
+		}
+		series = append(series, cp())
+	}
+
+	return series
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// collatz is the original big.Int loop that computes 3x+1 as (x<<1 | 1) + x
+func collatz(i *big.Int) []big.Int {
+	series := make([]big.Int, 0, 256)
+	cp := func() (z big.Int) {
+		z.Set(i)
+		return z
+	}
+	series = append(series, cp())
+	for one.Cmp(i) != 0 {
+		if i.Bit(0) == 0 {
+			i.Rsh(i, 1)
+		} else {
+			z := cp()
+			i.Lsh(i, 1).SetBit(i, 0, 1).Add(i, &z)
+		}
+		series = append(series, cp())
+	}
+	return series
+}
+
+// checkCollatz compares Collatz to collatz for n
+func checkCollatz(t *testing.T, n *big.Int) {
+	t.Helper()
+	got, err := Collatz(n)
+	if err != nil {
+		t.Fatalf("Collatz(%v): %v", n, err)
+	}
+	want := collatz(new(big.Int).Set(n))
+	if len(got) != len(want) {
+		t.Fatalf("Collatz(%v) has %d values, want %d", n, len(got), len(want))
+	}
+	for i := range want {
+		if got[i].Cmp(&want[i]) != 0 {
+			t.Fatalf("Collatz(%v)[%d] = %v, want %v", n, i, &got[i], &want[i])
+		}
+	}
+}
+
+func TestCollatz(t *testing.T) {
+	max := new(big.Int).Lsh(one, 128)
+	max.Sub(max, one)
+	third := new(big.Int).Div(max, big.NewInt(3))
+	starts := []*big.Int{
+		big.NewInt(1),
+		big.NewInt(2),
+		big.NewInt(27),
+		big.NewInt(77031),
+		// the largest start of the fast path overflows on its first step
+		max,
+		// (2^128-1)/3 is odd and triples to just below 2^128
+		third,
+		new(big.Int).Add(third, one),
+		new(big.Int).Sub(third, big.NewInt(2)),
+		new(big.Int).Lsh(one, 127),
+		new(big.Int).Lsh(one, 128),
+		new(big.Int).Add(max, big.NewInt(2)),
+	}
+	for _, n := range starts {
+		checkCollatz(t, n)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		bits := 1 + rng.Intn(140)
+		n := new(big.Int).Rand(rng, new(big.Int).Lsh(one, uint(bits)))
+		n.SetBit(n, bits-1, 1)
+		checkCollatz(t, n)
+	}
+
+	for _, n := range []int64{0, -1, -27} {
+		if _, err := Collatz(big.NewInt(n)); err != ErrNotPositive {
+			t.Errorf("Collatz(%d) = %v, want %v", n, err, ErrNotPositive)
+		}
+	}
+}
+
+func benchmarkCollatz(b *testing.B, trajectory func(n *big.Int)) {
+	starts := make([]*big.Int, 256)
+	for i := range starts {
+		starts[i] = big.NewInt(int64(1 + 2*i*7919))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		trajectory(starts[i%len(starts)])
+	}
+}
+
+func BenchmarkReference(b *testing.B) {
+	benchmarkCollatz(b, func(n *big.Int) {
+		collatz(new(big.Int).Set(n))
+	})
+}
+
+func BenchmarkCollatz(b *testing.B) {
+	benchmarkCollatz(b, func(n *big.Int) {
+		Collatz(n)
+	})
+}
@@ -0,0 +1,365 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"runtime"
+)
+
+// BlockSize is the number of starting values handed to a worker at a time
+const BlockSize = 1 << 14
+
+// RecordKind is the kind of a record
+type RecordKind int
+
+const (
+	// DelayRecord is a starting value whose total stopping time exceeds that of all smaller values
+	DelayRecord RecordKind = iota
+	// PathRecord is a starting value whose maximum excursion exceeds that of all smaller values
+	PathRecord
+)
+
+// String returns the name of the record kind
+func (k RecordKind) String() string {
+	switch k {
+	case DelayRecord:
+		return "delay"
+	case PathRecord:
+		return "path"
+	}
+	return "unknown"
+}
+
+// Record is a delay or path record
+type Record struct {
+	Kind      RecordKind
+	N         uint64
+	Delay     int
+	Excursion big.Int
+}
+
+// VerifyConfig configures Verify
+type VerifyConfig struct {
+	// Lo and Hi are the range [Lo, Hi) of starting values to verify
+	Lo, Hi uint64
+	// Workers is the number of workers, zero means runtime.NumCPU()
+	Workers int
+	// SieveBits is the sieve modulus exponent, zero disables the sieve
+	SieveBits int
+	// Records enables delay and path record tracking, this computes the full trajectory of every
+	// starting value so the sieve is not used
+	Records bool
+	// MaxSteps bounds the steps computed for a single starting value, zero means unlimited
+	MaxSteps int
+	// OnRecord is called in order for every new record
+	OnRecord func(Record)
+	// Resume continues a previous verification of the same range with the same Records and SieveBits
+	Resume *Verification
+	// OnProgress is called after each block with the state to checkpoint
+	OnProgress func(*Verification)
+	// Context stops the verification when it is done, the blocks verified so far are returned
+	// with the error of the context. Nil means the verification runs to the end
+	Context context.Context
+}
+
+// Verification is the outcome of Verify
+type Verification struct {
+	// Checked is the number of starting values that were iterated and Skipped the number removed by the sieve
+	Checked, Skipped uint64
+	// Delay and Path are the records found in order
+	Delay, Path []Record
+	// Done is the start of the range left to verify
+	Done uint64
+	// Tracked is true if records were tracked and SieveBits is the sieve used, zero with records
+	Tracked   bool
+	SieveBits int
+}
+
+// Records returns the delay and path records in the order they were found
+func (v *Verification) Records() []Record {
+	records := make([]Record, 0, len(v.Delay)+len(v.Path))
+	delay, path := v.Delay, v.Path
+	for len(delay) > 0 || len(path) > 0 {
+		if len(path) == 0 || (len(delay) > 0 && delay[0].N <= path[0].N) {
+			records, delay = append(records, delay[0]), delay[1:]
+		} else {
+			records, path = append(records, path[0]), path[1:]
+		}
+	}
+	return records
+}
+
+// ExceededError reports a starting value that exceeded the step budget
+type ExceededError struct {
+	N uint64
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("trajectory: %d exceeded the step budget", e.N)
+}
+
+// excursion is a maximum excursion that is only promoted to a big.Int when it outgrows 128 bits
+type excursion struct {
+	word uint128
+	big  *big.Int
+}
+
+func (e excursion) cmp(f excursion) int {
+	if e.big == nil && f.big == nil {
+		return e.word.cmp(f.word)
+	}
+	return e.toBigInt().Cmp(f.toBigInt())
+}
+
+func (e excursion) toBigInt() *big.Int {
+	if e.big != nil {
+		return e.big
+	}
+	return e.word.toBigInt()
+}
+
+// walk returns the total stopping time and maximum excursion of n
+func walk(n uint64, maxSteps int) (int, excursion, error) {
+	x, steps := uint128{lo: n}, 0
+	max := x
+	for !x.isOne() {
+		if maxSteps > 0 && steps >= maxSteps {
+			return 0, excursion{}, &ExceededError{N: n}
+		}
+		if x.lo&1 == 0 {
+			x = x.half()
+		} else {
+			y, ok := x.triple()
+			if !ok {
+				result, err := Standard.Run(new(big.Int).SetUint64(n), Limits{MaxSteps: maxSteps})
+				if err != nil {
+					return 0, excursion{}, err
+				}
+				if result.Status != StatusOne {
+					return 0, excursion{}, &ExceededError{N: n}
+				}
+				stats := NewStats(result.Series)
+				return stats.TotalStoppingTime, excursion{big: &stats.MaxExcursion}, nil
+			}
+			x = y
+			if x.cmp(max) > 0 {
+				max = x
+			}
+		}
+		steps++
+	}
+	return steps, excursion{word: max}, nil
+}
+
+// drop checks that n drops below itself or reaches 1
+func drop(n uint64, maxSteps int) error {
+	x, bound, steps := uint128{lo: n}, uint128{lo: n}, 0
+	for !x.isOne() && (steps == 0 || x.cmp(bound) >= 0) {
+		if maxSteps > 0 && steps >= maxSteps {
+			return &ExceededError{N: n}
+		}
+		if x.lo&1 == 0 {
+			x = x.half()
+		} else {
+			y, ok := x.triple()
+			if !ok {
+				result, err := Standard.Run(new(big.Int).SetUint64(n), Limits{MaxSteps: maxSteps})
+				if err != nil {
+					return err
+				}
+				if result.Status != StatusOne {
+					return &ExceededError{N: n}
+				}
+				return nil
+			}
+			x = y
+		}
+		steps++
+	}
+	return nil
+}
+
+// block is the result of verifying a block of starting values
+type block struct {
+	index            uint64
+	checked, skipped uint64
+	// delay and path are the records local to the block
+	delay, path []Record
+	err         error
+}
+
+// verifyBlock verifies the starting values in [lo, hi)
+func verifyBlock(config *VerifyConfig, sieve *Sieve, index, lo, hi uint64) block {
+	result := block{index: index}
+	var maxDelay int
+	var maxExcursion excursion
+	for n := lo; n < hi; n++ {
+		if !config.Records {
+			if sieve != nil && sieve.Skip(n) {
+				result.skipped++
+				continue
+			}
+			if err := drop(n, config.MaxSteps); err != nil {
+				result.err = err
+				return result
+			}
+			result.checked++
+			continue
+		}
+
+		delay, max, err := walk(n, config.MaxSteps)
+		if err != nil {
+			result.err = err
+			return result
+		}
+		result.checked++
+		if n == lo || delay > maxDelay {
+			maxDelay = delay
+			record := Record{Kind: DelayRecord, N: n, Delay: delay}
+			record.Excursion.Set(max.toBigInt())
+			result.delay = append(result.delay, record)
+		}
+		if n == lo || max.cmp(maxExcursion) > 0 {
+			maxExcursion = max
+			record := Record{Kind: PathRecord, N: n, Delay: delay}
+			record.Excursion.Set(max.toBigInt())
+			result.path = append(result.path, record)
+		}
+	}
+	return result
+}
+
+// Verify checks that every starting value in [Lo, Hi) reaches 1 using parallel workers
+func Verify(config VerifyConfig) (Verification, error) {
+	if config.Records {
+		config.SieveBits = 0
+	}
+	verification := Verification{Done: config.Lo, Tracked: config.Records, SieveBits: config.SieveBits}
+	if config.Lo < 1 {
+		return verification, errors.New("trajectory: range must start at 1 or more")
+	}
+	if config.Resume != nil {
+		if config.Resume.Done < config.Lo || config.Resume.Done > config.Hi {
+			return verification, errors.New("trajectory: resumed verification is outside of the range")
+		}
+		if config.Resume.Tracked != config.Records || config.Resume.SieveBits != config.SieveBits {
+			return verification, errors.New("trajectory: resumed verification has different records or sieve options")
+		}
+		verification = *config.Resume
+		config.Lo = verification.Done
+	}
+	if config.Hi <= config.Lo {
+		return verification, nil
+	}
+	var sieve *Sieve
+	if config.SieveBits > 0 {
+		var err error
+		sieve, err = NewSieve(config.SieveBits)
+		if err != nil {
+			return verification, err
+		}
+	}
+	workers := config.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	var cancelled <-chan struct{}
+	if config.Context != nil {
+		cancelled = config.Context.Done()
+	}
+	blocks := (config.Hi - config.Lo + BlockSize - 1) / BlockSize
+	indexes, results, done := make(chan uint64, workers), make(chan block, workers), make(chan struct{})
+	defer close(done)
+	for i := 0; i < workers; i++ {
+		go func() {
+			for index := range indexes {
+				lo := config.Lo + index*BlockSize
+				hi := lo + BlockSize
+				if hi > config.Hi || hi < lo {
+					hi = config.Hi
+				}
+				select {
+				case results <- verifyBlock(&config, sieve, index, lo, hi):
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(indexes)
+		for index := uint64(0); index < blocks; index++ {
+			select {
+			case indexes <- index:
+			case <-done:
+				return
+			case <-cancelled:
+				return
+			}
+		}
+	}()
+
+	pending, next := make(map[uint64]block), uint64(0)
+	for next < blocks {
+		var result block
+		select {
+		case result = <-results:
+		case <-cancelled:
+			return verification, config.Context.Err()
+		}
+		pending[result.index] = result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if result.err != nil {
+				return verification, result.err
+			}
+			verification.Checked += result.checked
+			verification.Skipped += result.skipped
+			verification.merge(result, config.OnRecord)
+			verification.Done = config.Lo + next*BlockSize
+			if verification.Done > config.Hi || verification.Done < config.Lo {
+				verification.Done = config.Hi
+			}
+			if config.OnProgress != nil {
+				config.OnProgress(&verification)
+			}
+		}
+	}
+	return verification, nil
+}
+
+// merge adds the records of a block that are also global records in order of starting value
+func (v *Verification) merge(result block, onRecord func(Record)) {
+	delay, path := result.delay, result.path
+	for len(delay) > 0 || len(path) > 0 {
+		var record Record
+		if len(path) == 0 || (len(delay) > 0 && delay[0].N <= path[0].N) {
+			record, delay = delay[0], delay[1:]
+			if length := len(v.Delay); length > 0 && record.Delay <= v.Delay[length-1].Delay {
+				continue
+			}
+			v.Delay = append(v.Delay, record)
+		} else {
+			record, path = path[0], path[1:]
+			if length := len(v.Path); length > 0 && record.Excursion.Cmp(&v.Path[length-1].Excursion) <= 0 {
+				continue
+			}
+			v.Path = append(v.Path, record)
+		}
+		if onRecord != nil {
+			onRecord(record)
+		}
+	}
+}
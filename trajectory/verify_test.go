@@ -0,0 +1,176 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// drops returns true if the trajectory of n > 1 falls below n
+func drops(n uint64) bool {
+	x := new(big.Int).SetUint64(n)
+	bound := new(big.Int).Set(x)
+	for {
+		Standard.Step(x)
+		if x.Cmp(bound) < 0 {
+			return true
+		}
+	}
+}
+
+func TestSieve(t *testing.T) {
+	for bits := 1; bits <= 12; bits++ {
+		sieve, err := NewSieve(bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		skipped := 0
+		for n := uint64(2); n < 1<<14; n++ {
+			if !sieve.Skip(n) {
+				continue
+			}
+			skipped++
+			if !drops(n) {
+				t.Fatalf("%d bits: skipped %d which does not drop below itself", bits, n)
+			}
+		}
+		if skipped == 0 {
+			t.Errorf("%d bits: nothing was skipped", bits)
+		}
+	}
+	for _, bits := range []int{0, MaxSieveBits + 1} {
+		if _, err := NewSieve(bits); err == nil {
+			t.Errorf("NewSieve(%d) succeeded", bits)
+		}
+	}
+}
+
+// sameRecords compares two lists of records
+func sameRecords(t *testing.T, name string, got, want []Record) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: %d records, want %d", name, len(got), len(want))
+	}
+	for i := range want {
+		g, w := &got[i], &want[i]
+		if g.Kind != w.Kind || g.N != w.N || g.Delay != w.Delay || g.Excursion.Cmp(&w.Excursion) != 0 {
+			t.Errorf("%s: record %d = %v %d %d %v, want %v %d %d %v", name, i,
+				g.Kind, g.N, g.Delay, &g.Excursion, w.Kind, w.N, w.Delay, &w.Excursion)
+		}
+	}
+}
+
+func TestVerifyRecords(t *testing.T) {
+	const hi = 100000
+	var found []Record
+	want, err := Verify(VerifyConfig{
+		Lo:      1,
+		Hi:      hi,
+		Workers: 1,
+		Records: true,
+		OnRecord: func(record Record) {
+			found = append(found, record)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Checked != hi-1 || want.Done != hi {
+		t.Fatalf("checked %d up to %d", want.Checked, want.Done)
+	}
+	delay, path := want.Delay[len(want.Delay)-1], want.Path[len(want.Path)-1]
+	if delay.N != 77031 || delay.Delay != 350 {
+		t.Errorf("last delay record = %d with delay %d, want 77031 with delay 350", delay.N, delay.Delay)
+	}
+	if path.N != 77671 || path.Excursion.Cmp(big.NewInt(1570824736)) != 0 {
+		t.Errorf("last path record = %d with excursion %v, want 77671 with excursion 1570824736", path.N, &path.Excursion)
+	}
+	sameRecords(t, "OnRecord", found, want.Records())
+
+	got, err := Verify(VerifyConfig{Lo: 1, Hi: hi, Workers: 7, Records: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sameRecords(t, "7 workers", got.Records(), want.Records())
+
+	partial, err := Verify(VerifyConfig{Lo: 1, Hi: 2*BlockSize + 77, Workers: 3, Records: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := Verify(VerifyConfig{Lo: 1, Hi: hi, Workers: 3, Records: true, Resume: &partial})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Checked != want.Checked || resumed.Done != want.Done {
+		t.Errorf("resumed verification checked %d up to %d", resumed.Checked, resumed.Done)
+	}
+	sameRecords(t, "resumed", resumed.Records(), want.Records())
+
+	if _, err := Verify(VerifyConfig{Lo: 1, Hi: hi, Resume: &partial}); err == nil {
+		t.Error("resumed a verification with records without them")
+	}
+	sieved, err := Verify(VerifyConfig{Lo: 1, Hi: 2 * BlockSize, SieveBits: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(VerifyConfig{Lo: 1, Hi: hi, SieveBits: 10, Resume: &sieved}); err == nil {
+		t.Error("resumed a verification with a different sieve")
+	}
+	if _, err := Verify(VerifyConfig{Lo: 1, Hi: hi, SieveBits: 8, Resume: &sieved}); err != nil {
+		t.Errorf("resumed a verification with the same sieve: %v", err)
+	}
+}
+
+func TestVerifyCancel(t *testing.T) {
+	const hi = 100 * BlockSize
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := 0
+	partial, err := Verify(VerifyConfig{
+		Lo:        1,
+		Hi:        hi,
+		Workers:   2,
+		SieveBits: 8,
+		Context:   ctx,
+		OnProgress: func(*Verification) {
+			if progress++; progress == 3 {
+				cancel()
+			}
+		},
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Verify = %v, want %v", err, context.Canceled)
+	}
+	if partial.Done < 1+3*BlockSize || partial.Done >= hi || partial.Checked+partial.Skipped != partial.Done-1 {
+		t.Fatalf("cancelled verification checked %d and skipped %d up to %d", partial.Checked, partial.Skipped, partial.Done)
+	}
+	resumed, err := Verify(VerifyConfig{Lo: 1, Hi: hi, SieveBits: 8, Resume: &partial})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Done != hi || resumed.Checked+resumed.Skipped != hi-1 {
+		t.Errorf("resumed verification checked %d and skipped %d up to %d", resumed.Checked, resumed.Skipped, resumed.Done)
+	}
+}
+
+func TestVerifySieve(t *testing.T) {
+	const hi = 100000
+	for _, workers := range []int{1, 4} {
+		verification, err := Verify(VerifyConfig{Lo: 1, Hi: hi, Workers: workers, SieveBits: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if verification.Checked+verification.Skipped != hi-1 || verification.Skipped == 0 {
+			t.Errorf("%d workers: checked %d and skipped %d", workers, verification.Checked, verification.Skipped)
+		}
+	}
+	_, err := Verify(VerifyConfig{Lo: 1, Hi: hi, MaxSteps: 10})
+	var exceeded *ExceededError
+	if !errors.As(err, &exceeded) || exceeded.N != 7 {
+		t.Errorf("Verify with a budget of 10 steps = %v, want 7 to exceed it", err)
+	}
+}
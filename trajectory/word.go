@@ -0,0 +1,112 @@
+// Copyright 2019 The Collatz Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package trajectory
+
+import (
+	"math/big"
+	"math/bits"
+)
+
+// wordsPerUint128 is the number of big.Words needed to hold a uint128
+const wordsPerUint128 = 128 / bits.UintSize
+
+// uint128 is an unsigned 128 bit integer
+type uint128 struct {
+	hi, lo uint64
+}
+
+// newUint128 converts n to a uint128, n must be non-negative and at most 128 bits long
+func newUint128(n *big.Int) (x uint128) {
+	for k, w := range n.Bits() {
+		shift := uint(k * bits.UintSize)
+		if shift < 64 {
+			x.lo |= uint64(w) << shift
+		} else {
+			x.hi |= uint64(w) << (shift - 64)
+		}
+	}
+	return x
+}
+
+// isOne returns true if x is 1
+func (x uint128) isOne() bool {
+	return x.hi == 0 && x.lo == 1
+}
+
+// half returns x/2
+func (x uint128) half() uint128 {
+	return uint128{
+		hi: x.hi >> 1,
+		lo: x.lo>>1 | x.hi<<63,
+	}
+}
+
+// triple returns 3x+1 and false if the result overflows 128 bits
+func (x uint128) triple() (uint128, bool) {
+	carry, lo := bits.Mul64(x.lo, 3)
+	overflow, hi := bits.Mul64(x.hi, 3)
+	if overflow != 0 {
+		return uint128{}, false
+	}
+	hi, c := bits.Add64(hi, carry, 0)
+	if c != 0 {
+		return uint128{}, false
+	}
+	lo, c = bits.Add64(lo, 1, 0)
+	hi, c = bits.Add64(hi, 0, c)
+	if c != 0 {
+		return uint128{}, false
+	}
+	return uint128{hi: hi, lo: lo}, true
+}
+
+// words stores x in w as little endian big.Words
+func (x uint128) words(w []big.Word) {
+	if bits.UintSize == 64 {
+		w[0], w[1] = big.Word(x.lo), big.Word(x.hi)
+		return
+	}
+	for k := range w {
+		shift := uint(k * bits.UintSize)
+		if shift < 64 {
+			w[k] = big.Word(x.lo >> shift)
+		} else {
+			w[k] = big.Word(x.hi >> (shift - 64))
+		}
+	}
+}
+
+// toBig converts values to big.Ints backed by a single allocation
+func toBig(values []uint128) []big.Int {
+	slab, series := make([]big.Word, len(values)*wordsPerUint128), make([]big.Int, len(values), len(values)+256)
+	for k, x := range values {
+		w := slab[k*wordsPerUint128 : (k+1)*wordsPerUint128 : (k+1)*wordsPerUint128]
+		x.words(w)
+		series[k].SetBits(w)
+	}
+	return series
+}
+
+// cmp compares x and y and returns -1, 0 or +1
+func (x uint128) cmp(y uint128) int {
+	switch {
+	case x.hi < y.hi:
+		return -1
+	case x.hi > y.hi:
+		return 1
+	case x.lo < y.lo:
+		return -1
+	case x.lo > y.lo:
+		return 1
+	}
+	return 0
+}
+
+// toBigInt converts x to a big.Int
+func (x uint128) toBigInt() *big.Int {
+	w := make([]big.Word, wordsPerUint128)
+	x.words(w)
+	return new(big.Int).SetBits(w)
+}